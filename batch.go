@@ -0,0 +1,132 @@
+package lbph
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Workers is the number of goroutines used by the batch functions
+// (PredictBatch and the feature extraction in the Train function).
+// If it is lower or equal to 0 (default), runtime.GOMAXPROCS is used.
+var Workers int
+
+// BatchResult struct stores the result of the Predict function for one image of the batch.
+// If an error has occurred (e.g. the image is nil or unknown), it is stored in Err.
+type BatchResult struct {
+	Label    string
+	Distance float64
+	Err      error
+}
+
+// ImageError struct stores the error that has occurred in one image and its index.
+type ImageError struct {
+	Index int
+	Err   error
+}
+
+// ExtractionError struct aggregates the errors that have occurred while
+// extracting the histograms of several images (sorted by image index).
+type ExtractionError struct {
+	Errors []ImageError
+}
+
+// Error function returns a message containing the index and error of each image.
+func (e *ExtractionError) Error() string {
+	var messages []string
+	for _, imageError := range e.Errors {
+		messages = append(messages, fmt.Sprintf("image %d: %v", imageError.Index, imageError.Err))
+	}
+	return fmt.Sprintf("Could not extract the histograms of %d images (%s)", len(e.Errors), strings.Join(messages, "; "))
+}
+
+// getWorkers function returns the number of goroutines used by the batch functions.
+func getWorkers(size int) int {
+	workers := Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > size {
+		workers = size
+	}
+	return workers
+}
+
+// parallelFor function calls the function passed by parameter for each index
+// (from 0 to size-1) using a pool of goroutines. It waits until all calls finish.
+func parallelFor(size int, fn func(index int)) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < getWorkers(size); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				fn(index)
+			}
+		}()
+	}
+
+	for index := 0; index < size; index++ {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// extractHistograms function calculates the histograms of the images in parallel,
+// preserving the images order. Each image is checked (not nil and with the size
+// passed by parameter) before the extraction. If an error occurs in one or more
+// images, all images are processed and an ExtractionError is returned.
+func extractHistograms(images []image.Image, width, height int) ([][]float64, error) {
+	histograms := make([][]float64, len(images))
+	errs := make([]error, len(images))
+
+	// The weights of the Mask are shared by all images
+	weights := getMaskWeights()
+	parallelFor(len(images), func(index int) {
+		if errs[index] = checkImageSize(images[index], width, height); errs[index] != nil {
+			return
+		}
+		histograms[index], errs[index] = extractHistogram(images[index], weights)
+	})
+
+	// Aggregate the errors by image index.
+	var extractionError ExtractionError
+	for index, err := range errs {
+		if err != nil {
+			extractionError.Errors = append(extractionError.Errors, ImageError{Index: index, Err: err})
+		}
+	}
+	if len(extractionError.Errors) > 0 {
+		return nil, &extractionError
+	}
+
+	return histograms, nil
+}
+
+// PredictBatch function is used to predict several images in parallel using a pool
+// of goroutines (defined by the Workers variable). Each image is predicted by the
+// Predict function and the results are returned in the same order as the images.
+// An error in one image does not stop the batch, it is stored in its BatchResult.
+func PredictBatch(images []image.Image) ([]BatchResult, error) {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return nil, errors.New("The algorithm was not trained yet")
+	}
+
+	// The weights of the Mask are shared by all images
+	weights := getMaskWeights()
+	results := make([]BatchResult, len(images))
+	parallelFor(len(images), func(index int) {
+		label, distance, err := predict(images[index], weights)
+		results[index] = BatchResult{Label: label, Distance: distance, Err: err}
+	})
+
+	return results, nil
+}
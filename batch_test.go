@@ -0,0 +1,168 @@
+package lbph
+
+import (
+	"image"
+	"testing"
+
+	"github.com/kelvins/lbph/lbp"
+	"github.com/kelvins/lbph/mask"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelFor(t *testing.T) {
+	defer func() { Workers = 0 }()
+
+	for _, workers := range []int{0, 1, 3, 100} {
+		Workers = workers
+		results := make([]int, 50)
+		parallelFor(len(results), func(index int) {
+			results[index] = index * 2
+		})
+		for index := 0; index < len(results); index++ {
+			assert.Equal(t, index*2, results[index])
+		}
+	}
+
+	// Nothing to do
+	parallelFor(0, func(index int) {
+		assert.Fail(t, "The function should not be called")
+	})
+}
+
+func TestPredictBatch(t *testing.T) {
+	Init(Params{})
+
+	_, err := PredictBatch(nil)
+	assert.NotNil(t, err)
+
+	trainDataset(t)
+	defer func() { Workers = 0 }()
+	Workers = 2
+
+	paths := []string{"./dataset/test/1.png", "./dataset/test/2.png", "./dataset/test/3.png"}
+	labels := []string{"wood", "rocks", "grass"}
+
+	var images []image.Image
+	for index := 0; index < len(paths); index++ {
+		img, err := LoadImage(paths[index])
+		assert.Nil(t, err)
+		images = append(images, img)
+	}
+
+	// A bad image should not fail the batch
+	images = append(images[:1], append([]image.Image{nil}, images[1:]...)...)
+	labels = append(labels[:1], append([]string{""}, labels[1:]...)...)
+
+	results, err := PredictBatch(images)
+	assert.Nil(t, err)
+	assert.Equal(t, len(images), len(results))
+
+	for index, result := range results {
+		if images[index] == nil {
+			assert.NotNil(t, result.Err)
+			continue
+		}
+		assert.Nil(t, result.Err)
+		assert.Equal(t, labels[index], result.Label)
+
+		_, distance, err := Predict(images[index])
+		assert.Nil(t, err)
+		assert.Equal(t, distance, result.Distance)
+	}
+
+	results, err = PredictBatch([]image.Image{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestExtractHistograms(t *testing.T) {
+	Init(Params{})
+	defer func() { Workers = 0 }()
+
+	var images []image.Image
+	for _, path := range []string{"./dataset/train/1.png", "./dataset/train/2.png", "./dataset/train/3.png"} {
+		img, err := LoadImage(path)
+		assert.Nil(t, err)
+		images = append(images, img)
+	}
+
+	// The results should be deterministic and in the same order as the images
+	Workers = 1
+	width, height := getImagesSize(images)
+	expected, err := extractHistograms(images, width, height)
+	assert.Nil(t, err)
+
+	Workers = 3
+	histograms, err := extractHistograms(images, width, height)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, histograms)
+
+	// The images that fail (a different size) are identified by index
+	small := image.NewGray(image.Rect(0, 0, 4, 4))
+	images = []image.Image{images[0], small, images[1], small}
+
+	_, err = extractHistograms(images, width, height)
+	assert.NotNil(t, err)
+
+	extractionError, ok := err.(*ExtractionError)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(extractionError.Errors))
+	assert.Equal(t, 1, extractionError.Errors[0].Index)
+	assert.Equal(t, 3, extractionError.Errors[1].Index)
+	assert.Contains(t, err.Error(), "image 1:")
+	assert.Contains(t, err.Error(), "image 3:")
+
+	// The Train function should return the aggregated error (too small for the grid)
+	err = Train([]image.Image{small, small}, []string{"a", "b"})
+	extractionError, ok = err.(*ExtractionError)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(extractionError.Errors))
+
+	// Only the nil images and the images with a different size fail
+	images = []image.Image{images[0], nil, images[2], small}
+	for _, fn := range []func() error{
+		func() error { return Train(images, []string{"a", "b", "c", "d"}) },
+		func() error { return Update(images, []string{"a", "b", "c", "d"}) },
+	} {
+		err = fn()
+		extractionError, ok = err.(*ExtractionError)
+		assert.True(t, ok)
+		assert.Equal(t, 2, len(extractionError.Errors))
+		assert.Equal(t, 1, extractionError.Errors[0].Index)
+		assert.Equal(t, 3, extractionError.Errors[1].Index)
+
+		// Train the algorithm, so the images are checked by Update
+		assert.Nil(t, Train(images[:1], []string{"a"}))
+	}
+
+	// The images with a different size from the training images fail
+	err = Update([]image.Image{small, small}, []string{"a", "b"})
+	extractionError, ok = err.(*ExtractionError)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(extractionError.Errors))
+}
+
+func TestPredictBatchMask(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Mask = nil
+	}()
+
+	// The weights of the mask are calculated once and shared by all images
+	images := trainSamples(t)
+	width, height := lbp.GetImageSize(images[0])
+	Mask = mask.Ellipse(width, height)
+	assert.Nil(t, Train(images, []string{"rocks", "grass", "wood", "wood", "rocks"}))
+
+	small := image.NewGray(image.Rect(0, 0, 8, 8))
+	results, err := PredictBatch(append(images, small))
+	assert.Nil(t, err)
+	for index, img := range images {
+		label, distance, err := Predict(img)
+		assert.Nil(t, err)
+		assert.Equal(t, BatchResult{Label: label, Distance: distance}, results[index])
+		assert.InDelta(t, 0, distance, 1e-9)
+	}
+	assert.EqualError(t, results[len(images)].Err, "The mask and the image have different sizes")
+}
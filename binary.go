@@ -0,0 +1,191 @@
+package lbph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// encoder struct is used to write the binary model format (little endian).
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) writeUint8(value uint8) {
+	e.buf.WriteByte(value)
+}
+
+func (e *encoder) writeUint16(value uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], value)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) writeUint32(value uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], value)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) writeUint64(value uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], value)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) writeFloat32(value float32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], math.Float32bits(value))
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) writeFloat64(value float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(value))
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) writeBytes(value []byte) {
+	e.writeUint32(uint32(len(value)))
+	e.buf.Write(value)
+}
+
+func (e *encoder) writeString(value string) {
+	e.writeBytes([]byte(value))
+}
+
+func (e *encoder) writeStrings(values []string) {
+	e.writeUint32(uint32(len(values)))
+	for _, value := range values {
+		e.writeString(value)
+	}
+}
+
+func (e *encoder) writeFloat64s(values []float64) {
+	e.writeUint32(uint32(len(values)))
+	for _, value := range values {
+		e.writeFloat64(value)
+	}
+}
+
+func (e *encoder) writeMatrix(values [][]float64) {
+	e.writeUint32(uint32(len(values)))
+	for _, row := range values {
+		e.writeFloat64s(row)
+	}
+}
+
+// errCorrupted is returned when the binary model is truncated or invalid.
+var errCorrupted = errors.New("The model is corrupted (unexpected end of data)")
+
+// decoder struct is used to read the binary model format (little endian).
+// After the first error all reads return zero values, so the error only
+// needs to be checked at the end.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+// next function returns the next size bytes of the data.
+func (d *decoder) next(size int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if size < 0 || size > len(d.data) {
+		d.err = errCorrupted
+		return nil
+	}
+	value := d.data[:size]
+	d.data = d.data[size:]
+	return value
+}
+
+func (d *decoder) readUint8() uint8 {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) readUint16() uint16 {
+	if b := d.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) readUint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) readUint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) readFloat32() float32 {
+	return math.Float32frombits(d.readUint32())
+}
+
+func (d *decoder) readFloat64() float64 {
+	if b := d.next(8); b != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+// readCount function reads a slice length and checks that the remaining data
+// has at least minSize bytes per element, so corrupted lengths do not allocate
+// huge slices.
+func (d *decoder) readCount(minSize int) int {
+	count := int(d.readUint32())
+	if d.err == nil && count*minSize > len(d.data) {
+		d.err = errCorrupted
+		return 0
+	}
+	return count
+}
+
+func (d *decoder) readBytes() []byte {
+	return d.next(d.readCount(1))
+}
+
+func (d *decoder) readString() string {
+	return string(d.readBytes())
+}
+
+func (d *decoder) readStrings() []string {
+	count := d.readCount(4)
+	var values []string
+	for index := 0; index < count && d.err == nil; index++ {
+		values = append(values, d.readString())
+	}
+	return values
+}
+
+func (d *decoder) readFloat64s() []float64 {
+	count := d.readCount(8)
+	if d.err != nil {
+		return nil
+	}
+	values := make([]float64, count)
+	for index := 0; index < count; index++ {
+		values[index] = d.readFloat64()
+	}
+	return values
+}
+
+func (d *decoder) readMatrix() [][]float64 {
+	count := d.readCount(4)
+	var values [][]float64
+	for index := 0; index < count && d.err == nil; index++ {
+		values = append(values, d.readFloat64s())
+	}
+	return values
+}
@@ -0,0 +1,84 @@
+package lbph
+
+import (
+	"errors"
+	"image"
+
+	"github.com/kelvins/lbph/calibration"
+)
+
+// getCalibrationDistances function compares the validation images to the training
+// images and returns the genuine distances (closest training image with the same
+// label) and the impostor distances (closest training image with another label).
+func getCalibrationDistances(images []image.Image, labels []string) ([]float64, []float64, error) {
+	var genuine, impostor []float64
+
+	for index := 0; index < len(images); index++ {
+		// Compare the image to all training images.
+		predictions, err := PredictTopK(images[index], getGallery().size())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// As the predictions are sorted, the first ones are the closest.
+		foundGenuine, foundImpostor := false, false
+		for _, prediction := range predictions {
+			if prediction.Label == labels[index] && !foundGenuine {
+				genuine = append(genuine, prediction.Distance)
+				foundGenuine = true
+			}
+			if prediction.Label != labels[index] && !foundImpostor {
+				impostor = append(impostor, prediction.Distance)
+				foundImpostor = true
+			}
+		}
+	}
+
+	return genuine, impostor, nil
+}
+
+// Calibrate function fits the calibration used to map the distances to confidence
+// scores, based on validation images and labels (different from the training ones).
+// The fit function defines the calibration method (e.g. calibration.FitLogistic or
+// calibration.FitIsotonic). The calibration is stored in the training data, so the
+// algorithm must be trained first and the Metric should not be changed afterwards.
+func Calibrate(images []image.Image, labels []string, fit calibration.FitFunc) error {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return errors.New("The algorithm was not trained yet")
+	}
+
+	// Check the parameters.
+	if len(images) == 0 || len(labels) == 0 {
+		return errors.New("At least one of the slices is empty")
+	}
+	if len(images) != len(labels) {
+		return errors.New("The slices have different sizes")
+	}
+	if fit == nil {
+		return errors.New("The fit function passed by parameter is nil")
+	}
+
+	genuine, impostor, err := getCalibrationDistances(images, labels)
+	if err != nil {
+		return err
+	}
+
+	calibrator, err := fit(genuine, impostor)
+	if err != nil {
+		return err
+	}
+
+	trainingData.Calibration = calibrator
+	return nil
+}
+
+// Confidence function maps a distance returned by the Predict step to a
+// probability-like confidence (between 0 and 1) using the fitted calibration.
+func Confidence(distance float64) (float64, error) {
+	if trainingData == nil || trainingData.Calibration == nil {
+		return 0.0, errors.New("The calibration was not fitted yet")
+	}
+	return trainingData.Calibration.Confidence(distance), nil
+}
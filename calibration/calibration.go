@@ -0,0 +1,242 @@
+// calibration package provides the functions used to map the distances returned
+// by the Predict step to probability-like confidence scores (between 0 and 1).
+// The calibration is fitted using genuine distances (from images compared to the
+// same label) and impostor distances (from images compared to other labels).
+package calibration
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Calibrator interface is implemented by the fitted calibrations.
+// The Confidence function maps a distance to a confidence between 0 and 1
+// (the lower the distance, the higher the confidence).
+type Calibrator interface {
+	Confidence(distance float64) float64
+}
+
+// FitFunc type defines the functions used to fit a calibration (e.g. FitLogistic).
+type FitFunc func(genuine, impostor []float64) (Calibrator, error)
+
+// checkDistances function checks if the genuine and impostor distances are valid.
+func checkDistances(genuine, impostor []float64) error {
+	if len(genuine) == 0 || len(impostor) == 0 {
+		return errors.New("The genuine and impostor distances should not be empty")
+	}
+	for _, distances := range [][]float64{genuine, impostor} {
+		for _, distance := range distances {
+			if math.IsNaN(distance) || math.IsInf(distance, 0) {
+				return errors.New("The distances should be finite numbers")
+			}
+		}
+	}
+	return nil
+}
+
+// Logistic struct implements the Platt (logistic) calibration:
+// confidence = 1 / (1 + exp(A * distance + B))
+type Logistic struct {
+	A float64
+	B float64
+}
+
+// Confidence function maps the distance to a confidence using the logistic function.
+func (l Logistic) Confidence(distance float64) float64 {
+	return 1 / (1 + math.Exp(l.A*distance+l.B))
+}
+
+// FitLogistic function fits the Platt (logistic) calibration using the Newton method
+// with backtracking line search and the target smoothing proposed by Platt.
+// Reference:
+// Lin, Hsuan-Tien, Chih-Jen Lin, and Ruby C. Weng. "A note on Platt's probabilistic outputs for support vector machines."
+func FitLogistic(genuine, impostor []float64) (Calibrator, error) {
+	if err := checkDistances(genuine, impostor); err != nil {
+		return nil, err
+	}
+
+	// Smoothed targets (genuine = 1, impostor = 0)
+	highTarget := (float64(len(genuine)) + 1) / (float64(len(genuine)) + 2)
+	lowTarget := 1 / (float64(len(impostor)) + 2)
+
+	distances := append(append([]float64{}, genuine...), impostor...)
+	targets := make([]float64, len(distances))
+	for index := range distances {
+		if index < len(genuine) {
+			targets[index] = highTarget
+		} else {
+			targets[index] = lowTarget
+		}
+	}
+
+	// Negative log-likelihood of the parameters
+	objective := func(a, b float64) float64 {
+		var value float64
+		for index, distance := range distances {
+			f := a*distance + b
+			if f >= 0 {
+				value += targets[index]*f + math.Log(1+math.Exp(-f))
+			} else {
+				value += (targets[index]-1)*f + math.Log(1+math.Exp(f))
+			}
+		}
+		return value
+	}
+
+	const maxIterations = 100
+	const minStep = 1e-10
+	const sigma = 1e-12
+
+	a := 0.0
+	b := math.Log((float64(len(impostor)) + 1) / (float64(len(genuine)) + 1))
+	value := objective(a, b)
+
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		// Gradient and Hessian (H' = H + sigma I)
+		h11, h22, h21 := sigma, sigma, 0.0
+		g1, g2 := 0.0, 0.0
+		for index, distance := range distances {
+			f := a*distance + b
+			var p, q float64
+			if f >= 0 {
+				p = math.Exp(-f) / (1 + math.Exp(-f))
+				q = 1 / (1 + math.Exp(-f))
+			} else {
+				p = 1 / (1 + math.Exp(f))
+				q = math.Exp(f) / (1 + math.Exp(f))
+			}
+			d2 := p * q
+			h11 += distance * distance * d2
+			h22 += d2
+			h21 += distance * d2
+			d1 := targets[index] - p
+			g1 += distance * d1
+			g2 += d1
+		}
+
+		// Stopping criteria
+		if math.Abs(g1) < 1e-5 && math.Abs(g2) < 1e-5 {
+			break
+		}
+
+		// Newton direction: -inv(H') * g
+		det := h11*h22 - h21*h21
+		dA := -(h22*g1 - h21*g2) / det
+		dB := -(-h21*g1 + h11*g2) / det
+		gd := g1*dA + g2*dB
+
+		// Line search
+		step := 1.0
+		for step >= minStep {
+			newA := a + step*dA
+			newB := b + step*dB
+			newValue := objective(newA, newB)
+			if newValue < value+0.0001*step*gd {
+				a, b, value = newA, newB, newValue
+				break
+			}
+			step /= 2
+		}
+		if step < minStep {
+			break
+		}
+	}
+
+	return Logistic{A: a, B: b}, nil
+}
+
+// Isotonic struct implements the isotonic calibration: a non-increasing piecewise
+// linear function defined by the points (Distances, Confidences). Distances lower
+// (higher) than the first (last) point use the first (last) confidence.
+type Isotonic struct {
+	Distances   []float64
+	Confidences []float64
+}
+
+// Confidence function maps the distance to a confidence using linear interpolation.
+func (iso Isotonic) Confidence(distance float64) float64 {
+	if len(iso.Distances) == 0 {
+		return 0
+	}
+
+	// Find the first point with a distance higher or equal to the distance
+	index := sort.SearchFloat64s(iso.Distances, distance)
+	if index == 0 {
+		return iso.Confidences[0]
+	}
+	if index == len(iso.Distances) {
+		return iso.Confidences[len(iso.Confidences)-1]
+	}
+
+	// Linear interpolation between the points
+	x0, x1 := iso.Distances[index-1], iso.Distances[index]
+	y0, y1 := iso.Confidences[index-1], iso.Confidences[index]
+	return y0 + (y1-y0)*(distance-x0)/(x1-x0)
+}
+
+// FitIsotonic function fits the isotonic calibration (non-increasing confidence)
+// using the pool adjacent violators algorithm.
+// Reference:
+// Zadrozny, Bianca, and Charles Elkan. "Transforming classifier scores into accurate multiclass probability estimates."
+func FitIsotonic(genuine, impostor []float64) (Calibrator, error) {
+	if err := checkDistances(genuine, impostor); err != nil {
+		return nil, err
+	}
+
+	type point struct {
+		distance float64
+		target   float64
+	}
+
+	var points []point
+	for _, distance := range genuine {
+		points = append(points, point{distance, 1})
+	}
+	for _, distance := range impostor {
+		points = append(points, point{distance, 0})
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].distance < points[j].distance
+	})
+
+	// Each block stores the sum of the targets, the number of points and the distance interval
+	type block struct {
+		sum, count       float64
+		minDist, maxDist float64
+	}
+
+	var blocks []block
+	for _, p := range points {
+		current := block{sum: p.target, count: 1, minDist: p.distance, maxDist: p.distance}
+
+		// Merge the points with the same distance and the blocks
+		// that violate the non-increasing constraint
+		for len(blocks) > 0 {
+			last := blocks[len(blocks)-1]
+			if last.maxDist != current.minDist && last.sum/last.count >= current.sum/current.count {
+				break
+			}
+			current = block{
+				sum:     last.sum + current.sum,
+				count:   last.count + current.count,
+				minDist: last.minDist,
+				maxDist: current.maxDist,
+			}
+			blocks = blocks[:len(blocks)-1]
+		}
+		blocks = append(blocks, current)
+	}
+
+	var calibrator Isotonic
+	for _, b := range blocks {
+		confidence := b.sum / b.count
+		calibrator.Distances = append(calibrator.Distances, b.minDist)
+		calibrator.Confidences = append(calibrator.Confidences, confidence)
+		if b.maxDist != b.minDist {
+			calibrator.Distances = append(calibrator.Distances, b.maxDist)
+			calibrator.Confidences = append(calibrator.Confidences, confidence)
+		}
+	}
+	return calibrator, nil
+}
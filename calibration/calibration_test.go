@@ -0,0 +1,94 @@
+package calibration
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFitLogistic(t *testing.T) {
+	genuine := []float64{1.0, 1.5, 2.0, 2.5, 3.0, 4.5}
+	impostor := []float64{3.5, 4.0, 5.0, 5.5, 6.0, 2.8}
+
+	calibrator, err := FitLogistic(genuine, impostor)
+	assert.Nil(t, err)
+
+	logistic, ok := calibrator.(Logistic)
+	assert.True(t, ok)
+
+	// The confidence should decrease with the distance
+	assert.True(t, logistic.A > 0)
+	assert.True(t, calibrator.Confidence(1.0) > 0.8)
+	assert.True(t, calibrator.Confidence(6.0) < 0.2)
+	assert.InDelta(t, 0.5, calibrator.Confidence(-logistic.B/logistic.A), 1e-12)
+	for distance := 0.0; distance < 7.0; distance += 0.5 {
+		assert.True(t, calibrator.Confidence(distance) > calibrator.Confidence(distance+0.5))
+	}
+
+	// The fitted parameters should minimize the objective (zero gradient)
+	highTarget := 7.0 / 8.0
+	lowTarget := 1.0 / 8.0
+	var g1, g2 float64
+	for _, distance := range genuine {
+		g1 += distance * (highTarget - calibrator.Confidence(distance))
+		g2 += highTarget - calibrator.Confidence(distance)
+	}
+	for _, distance := range impostor {
+		g1 += distance * (lowTarget - calibrator.Confidence(distance))
+		g2 += lowTarget - calibrator.Confidence(distance)
+	}
+	assert.InDelta(t, 0.0, g1, 1e-4)
+	assert.InDelta(t, 0.0, g2, 1e-4)
+
+	_, err = FitLogistic(nil, impostor)
+	assert.NotNil(t, err)
+
+	_, err = FitLogistic(genuine, []float64{math.Inf(1)})
+	assert.NotNil(t, err)
+}
+
+func TestFitIsotonic(t *testing.T) {
+	genuine := []float64{1, 2, 4}
+	impostor := []float64{3, 5, 6}
+
+	calibrator, err := FitIsotonic(genuine, impostor)
+	assert.Nil(t, err)
+
+	expected := Isotonic{
+		Distances:   []float64{1, 2, 3, 4, 5, 6},
+		Confidences: []float64{1, 1, 0.5, 0.5, 0, 0},
+	}
+	assert.Equal(t, expected, calibrator)
+
+	// Table tests
+	var tTable = []struct {
+		distance   float64
+		confidence float64
+	}{
+		{0, 1},
+		{2, 1},
+		{2.5, 0.75},
+		{3.5, 0.5},
+		{4.5, 0.25},
+		{10, 0},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		assert.InDelta(t, pair.confidence, calibrator.Confidence(pair.distance), 1e-12)
+	}
+
+	// Points with the same distance are merged
+	calibrator, err = FitIsotonic([]float64{1, 2}, []float64{2, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, Isotonic{Distances: []float64{1, 2, 3}, Confidences: []float64{1, 0.5, 0}}, calibrator)
+
+	assert.Equal(t, 0.0, Isotonic{}.Confidence(1))
+
+	_, err = FitIsotonic(genuine, nil)
+	assert.NotNil(t, err)
+
+	_, err = FitIsotonic([]float64{math.NaN()}, impostor)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,58 @@
+package lbph
+
+import (
+	"image"
+	"testing"
+
+	"github.com/kelvins/lbph/calibration"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalibrate(t *testing.T) {
+	Init(Params{})
+
+	_, err := Confidence(1.0)
+	assert.NotNil(t, err)
+
+	trainDataset(t)
+
+	_, err = Confidence(1.0)
+	assert.NotNil(t, err)
+
+	paths := []string{"./dataset/test/1.png", "./dataset/test/2.png", "./dataset/test/3.png"}
+	labels := []string{"wood", "rocks", "grass"}
+
+	var images []image.Image
+	for index := 0; index < len(paths); index++ {
+		img, err := LoadImage(paths[index])
+		assert.Nil(t, err)
+		images = append(images, img)
+	}
+
+	err = Calibrate(images, labels[:2], calibration.FitLogistic)
+	assert.NotNil(t, err)
+
+	err = Calibrate(images, labels, nil)
+	assert.NotNil(t, err)
+
+	for _, fit := range []calibration.FitFunc{calibration.FitLogistic, calibration.FitIsotonic} {
+		err = Calibrate(images, labels, fit)
+		assert.Nil(t, err)
+		assert.NotNil(t, GetTrainingData().Calibration)
+
+		// The genuine distances should have a higher confidence
+		_, distance, err := Predict(images[0])
+		assert.Nil(t, err)
+		genuineConfidence, err := Confidence(distance)
+		assert.Nil(t, err)
+
+		predictions, err := PredictTopK(images[0], 3)
+		assert.Nil(t, err)
+		impostorConfidence, err := Confidence(predictions[2].Distance)
+		assert.Nil(t, err)
+
+		assert.True(t, genuineConfidence > impostorConfidence)
+		assert.True(t, genuineConfidence <= 1 && impostorConfidence >= 0)
+	}
+}
@@ -0,0 +1,211 @@
+// compact package provides compact representations of the histograms (float32,
+// uint16 counts and sparse) used to reduce the memory of large galleries, and
+// the functions used to compare them without converting them back to float64.
+package compact
+
+import (
+	"errors"
+	"math"
+)
+
+// Storage type defines the representation of the histograms.
+type Storage string
+
+// Storages supported by the New function.
+const (
+	// Float64 stores the histograms without compression (default).
+	Float64 Storage = "Float64"
+	// Float32 stores each bin as a float32 (half of the memory).
+	Float32 Storage = "Float32"
+	// Uint16 stores each bin as an uint16 count. Non-integer histograms (e.g.
+	// normalized or weighted by a mask) are quantized using a scale factor.
+	Uint16 Storage = "Uint16"
+	// Sparse stores only the non-empty bins (index and float32 value).
+	Sparse Storage = "Sparse"
+)
+
+// errEncodedStorage is returned when the storage does not have a fixed size.
+var errEncodedStorage = errors.New("The storage does not have a fixed size (only Float64, Float32 and Uint16 are supported)")
+
+// Histogram interface is implemented by the compact histograms.
+type Histogram interface {
+	// Storage function returns the representation of the histogram.
+	Storage() Storage
+	// Len function returns the number of bins of the histogram.
+	Len() int
+	// Float64s function returns the (decoded) bins of the histogram.
+	Float64s() []float64
+	// accumulate function returns the sum of fn(query, bin) for all bins.
+	accumulate(query []float64, fn binFunc) float64
+}
+
+// binFunc type defines the contribution of a bin to a metric.
+type binFunc func(query, value float64) float64
+
+// Float64Histogram type stores the histogram without compression.
+type Float64Histogram []float64
+
+// Float32Histogram type stores each bin as a float32.
+type Float32Histogram []float32
+
+// Uint16Histogram struct stores each bin as an uint16 count multiplied by the Scale.
+type Uint16Histogram struct {
+	Scale  float64
+	Counts []uint16
+}
+
+// SparseHistogram struct stores only the non-empty bins (Indexes and Values)
+// of a histogram with Size bins. The indexes are sorted in ascending order.
+type SparseHistogram struct {
+	Size    int
+	Indexes []uint32
+	Values  []float32
+}
+
+// New function converts the histogram passed by parameter to the selected storage.
+// An empty storage is the same as Float64.
+func New(hist []float64, storage Storage) (Histogram, error) {
+	switch storage {
+	case Float64, "":
+		return Float64Histogram(append([]float64{}, hist...)), nil
+	case Float32:
+		converted := make(Float32Histogram, len(hist))
+		for index, value := range hist {
+			converted[index] = float32(value)
+		}
+		return converted, nil
+	case Uint16:
+		return newUint16(hist)
+	case Sparse:
+		converted := SparseHistogram{Size: len(hist)}
+		for index, value := range hist {
+			if value != 0 {
+				converted.Indexes = append(converted.Indexes, uint32(index))
+				converted.Values = append(converted.Values, float32(value))
+			}
+		}
+		return converted, nil
+	default:
+		return nil, errors.New("Invalid storage selected to store the histograms")
+	}
+}
+
+// newUint16 function converts the histogram to uint16 counts. If all bins are
+// integers lower or equal to 65535 they are stored exactly (scale 1), otherwise
+// they are quantized, so the highest bin is stored as 65535.
+func newUint16(hist []float64) (Histogram, error) {
+	var max float64
+	integers := true
+	for _, value := range hist {
+		if value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, errors.New("The uint16 storage only supports positive and finite bins")
+		}
+		if value != math.Trunc(value) {
+			integers = false
+		}
+		max = math.Max(max, value)
+	}
+
+	converted := Uint16Histogram{Scale: 1, Counts: make([]uint16, len(hist))}
+	if (!integers || max > math.MaxUint16) && max > 0 {
+		converted.Scale = max / math.MaxUint16
+	}
+	for index, value := range hist {
+		converted.Counts[index] = uint16(math.Min(math.Floor(value/converted.Scale+0.5), math.MaxUint16))
+	}
+	return converted, nil
+}
+
+// Storage function returns the representation of the histogram.
+func (hist Float64Histogram) Storage() Storage { return Float64 }
+
+// Len function returns the number of bins of the histogram.
+func (hist Float64Histogram) Len() int { return len(hist) }
+
+// Float64s function returns a copy of the bins of the histogram.
+func (hist Float64Histogram) Float64s() []float64 {
+	return append([]float64{}, hist...)
+}
+
+func (hist Float64Histogram) accumulate(query []float64, fn binFunc) float64 {
+	var sum float64
+	for index, value := range hist {
+		sum += fn(query[index], value)
+	}
+	return sum
+}
+
+// Storage function returns the representation of the histogram.
+func (hist Float32Histogram) Storage() Storage { return Float32 }
+
+// Len function returns the number of bins of the histogram.
+func (hist Float32Histogram) Len() int { return len(hist) }
+
+// Float64s function returns the decoded bins of the histogram.
+func (hist Float32Histogram) Float64s() []float64 {
+	decoded := make([]float64, len(hist))
+	for index, value := range hist {
+		decoded[index] = float64(value)
+	}
+	return decoded
+}
+
+func (hist Float32Histogram) accumulate(query []float64, fn binFunc) float64 {
+	var sum float64
+	for index, value := range hist {
+		sum += fn(query[index], float64(value))
+	}
+	return sum
+}
+
+// Storage function returns the representation of the histogram.
+func (hist Uint16Histogram) Storage() Storage { return Uint16 }
+
+// Len function returns the number of bins of the histogram.
+func (hist Uint16Histogram) Len() int { return len(hist.Counts) }
+
+// Float64s function returns the decoded bins of the histogram.
+func (hist Uint16Histogram) Float64s() []float64 {
+	decoded := make([]float64, len(hist.Counts))
+	for index, count := range hist.Counts {
+		decoded[index] = float64(count) * hist.Scale
+	}
+	return decoded
+}
+
+func (hist Uint16Histogram) accumulate(query []float64, fn binFunc) float64 {
+	var sum float64
+	for index, count := range hist.Counts {
+		sum += fn(query[index], float64(count)*hist.Scale)
+	}
+	return sum
+}
+
+// Storage function returns the representation of the histogram.
+func (hist SparseHistogram) Storage() Storage { return Sparse }
+
+// Len function returns the number of bins of the histogram.
+func (hist SparseHistogram) Len() int { return hist.Size }
+
+// Float64s function returns the decoded bins of the histogram.
+func (hist SparseHistogram) Float64s() []float64 {
+	decoded := make([]float64, hist.Size)
+	for index, position := range hist.Indexes {
+		decoded[position] = float64(hist.Values[index])
+	}
+	return decoded
+}
+
+// accumulate function adds the contribution of the empty bins, fn(query, 0), and
+// replaces it by fn(query, value) for the stored bins.
+func (hist SparseHistogram) accumulate(query []float64, fn binFunc) float64 {
+	var sum float64
+	for _, value := range query {
+		sum += fn(value, 0)
+	}
+	for index, position := range hist.Indexes {
+		value := query[position]
+		sum += fn(value, float64(hist.Values[index])) - fn(value, 0)
+	}
+	return sum
+}
@@ -0,0 +1,132 @@
+package compact
+
+import (
+	"testing"
+
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	hist := []float64{0, 3, 0, 0, 70000, 0.5}
+
+	_, err := New(hist, Storage("Invalid"))
+	assert.NotNil(t, err)
+
+	var tTable = []struct {
+		storage  Storage
+		expected Histogram
+	}{
+		{"", Float64Histogram{0, 3, 0, 0, 70000, 0.5}},
+		{Float64, Float64Histogram{0, 3, 0, 0, 70000, 0.5}},
+		{Float32, Float32Histogram{0, 3, 0, 0, 70000, 0.5}},
+		{Sparse, SparseHistogram{Size: 6, Indexes: []uint32{1, 4, 5}, Values: []float32{3, 70000, 0.5}}},
+	}
+
+	for _, pair := range tTable {
+		converted, err := New(hist, pair.storage)
+		assert.Nil(t, err)
+		assert.Equal(t, pair.expected, converted)
+		assert.Equal(t, len(hist), converted.Len())
+		assert.Equal(t, hist, converted.Float64s())
+	}
+
+	// Integer counts are stored exactly
+	converted, err := New([]float64{0, 3, 65535}, Uint16)
+	assert.Nil(t, err)
+	assert.Equal(t, Uint16Histogram{Scale: 1, Counts: []uint16{0, 3, 65535}}, converted)
+	assert.Equal(t, []float64{0, 3, 65535}, converted.Float64s())
+
+	// Other histograms are quantized
+	converted, err = New(hist, Uint16)
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(65535), converted.(Uint16Histogram).Counts[4])
+	assert.InDeltaSlice(t, hist, converted.Float64s(), 1)
+
+	_, err = New([]float64{-1, 2}, Uint16)
+	assert.NotNil(t, err)
+}
+
+func TestDistance(t *testing.T) {
+	query := []float64{1, 0, 4, 2, 0, 3, 0, 8}
+	hist := []float64{0, 0, 4, 1, 0, 5, 0, 6}
+
+	names := []metric.Name{
+		metric.ChiSquare, metric.EuclideanDistance, metric.NormalizedEuclideanDistance,
+		metric.AbsoluteValue, metric.CosineDistance, metric.Intersection, metric.HellingerDistance,
+	}
+	storages := []Storage{Float64, Float32, Uint16, Sparse}
+
+	for _, name := range names {
+		expected, err := name.Distance(query, hist)
+		assert.Nil(t, err)
+
+		for _, storage := range storages {
+			converted, err := New(hist, storage)
+			assert.Nil(t, err)
+
+			distance, err := Distance(query, converted, name)
+			assert.Nil(t, err)
+			assert.InDelta(t, expected, distance, 1e-9, "%s %s", name, storage)
+		}
+	}
+
+	// Custom metrics compare the decoded histogram
+	custom := metric.New("Custom", func(hist1, hist2 []float64) (float64, error) {
+		return hist2[7], nil
+	})
+	converted, _ := New(hist, Sparse)
+	distance, err := Distance(query, converted, custom)
+	assert.Nil(t, err)
+	assert.Equal(t, 6.0, distance)
+
+	// Invalid parameters
+	_, err = Distance(query, converted, nil)
+	assert.NotNil(t, err)
+	_, err = Distance(query[:4], converted, metric.ChiSquare)
+	assert.NotNil(t, err)
+	_, err = Distance(nil, converted, metric.ChiSquare)
+	assert.NotNil(t, err)
+}
+
+func BenchmarkDistance(b *testing.B) {
+	query := make([]float64, 256*64)
+	hist := make([]float64, 256*64)
+	for index := 0; index < len(hist); index += 7 {
+		query[index] = float64(index % 13)
+		hist[index] = float64(index % 11)
+	}
+
+	for _, storage := range []Storage{Float64, Float32, Uint16, Sparse} {
+		converted, _ := New(hist, storage)
+		b.Run(string(storage), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				Distance(query, converted, metric.ChiSquare)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	hist := []float64{0, 3, 0, 0, 7, 1}
+
+	for _, storage := range []Storage{Float64, Float32, Uint16} {
+		data, scale, err := Encode(nil, hist, storage)
+		assert.Nil(t, err)
+		assert.Equal(t, len(hist)*Size(storage), len(data))
+
+		encoded := EncodedHistogram{Type: storage, Scale: scale, Data: data}
+		assert.Equal(t, len(hist), encoded.Len())
+		assert.Equal(t, hist, encoded.Float64s())
+
+		distance, err := Distance([]float64{1, 3, 0, 0, 5, 1}, encoded, metric.EuclideanDistance)
+		assert.Nil(t, err)
+		assert.InDelta(t, 2.236068, distance, 1e-6)
+	}
+
+	_, _, err := Encode(nil, hist, Sparse)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, Size(Sparse))
+}
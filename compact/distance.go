@@ -0,0 +1,88 @@
+package compact
+
+import (
+	"errors"
+	"math"
+
+	"github.com/kelvins/lbph/metric"
+)
+
+// binFuncs are the metrics that are calculated directly on the compact
+// histograms. They are the sum of the contributions of each bin.
+var binFuncs = map[string]binFunc{
+	string(metric.ChiSquare): func(query, value float64) float64 {
+		denominator := query + value
+		if denominator == 0 {
+			return 0
+		}
+		return (query - value) * (query - value) / denominator
+	},
+	string(metric.EuclideanDistance): func(query, value float64) float64 {
+		return (query - value) * (query - value)
+	},
+	string(metric.NormalizedEuclideanDistance): func(query, value float64) float64 {
+		return (query - value) * (query - value)
+	},
+	string(metric.AbsoluteValue): func(query, value float64) float64 {
+		return math.Abs(query - value)
+	},
+}
+
+// Distance function compares the query histogram to the compact histogram using
+// the selected metric. The ChiSquare, EuclideanDistance, NormalizedEuclideanDistance,
+// AbsoluteValue and CosineDistance metrics are calculated directly on the compact
+// histogram (without allocating), other metrics compare the decoded histogram.
+func Distance(query []float64, hist Histogram, selectedMetric metric.Metric) (float64, error) {
+	if selectedMetric == nil {
+		return 0, errors.New("Invalid metric selected to compare the histograms")
+	}
+	if hist == nil || len(query) == 0 || hist.Len() == 0 {
+		return 0, errors.New("Could not compare the histograms. The histogram is empty.")
+	}
+	if len(query) != hist.Len() {
+		return 0, errors.New("Could not compare the histograms. The slices have different sizes.")
+	}
+
+	// Custom metrics cannot use the names of the built-in metrics, so the name
+	// identifies the metric. The fitted metrics (e.g. Mahalanobis) are decoded.
+	name := selectedMetric.Name()
+	if _, ok := selectedMetric.(metric.Name); !ok {
+		return selectedMetric.Distance(query, hist.Float64s())
+	}
+
+	if fn, ok := binFuncs[name]; ok {
+		sum := hist.accumulate(query, fn)
+		switch metric.Name(name) {
+		case metric.EuclideanDistance:
+			return math.Sqrt(sum), nil
+		case metric.NormalizedEuclideanDistance:
+			return math.Sqrt(sum / float64(len(query))), nil
+		}
+		return sum, nil
+	}
+
+	if metric.Name(name) == metric.CosineDistance {
+		return cosineDistance(query, hist), nil
+	}
+
+	return selectedMetric.Distance(query, hist.Float64s())
+}
+
+// cosineDistance function calculates the cosine distance (same as math.CosineDistance).
+func cosineDistance(query []float64, hist Histogram) float64 {
+	dot := hist.accumulate(query, func(query, value float64) float64 { return query * value })
+	norm2 := hist.accumulate(query, func(query, value float64) float64 { return value * value })
+
+	var norm1 float64
+	for _, value := range query {
+		norm1 += value * value
+	}
+
+	if norm1 == 0 || norm2 == 0 {
+		if norm1 == norm2 {
+			return 0
+		}
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(norm1)*math.Sqrt(norm2))
+}
@@ -0,0 +1,105 @@
+package compact
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// EncodedHistogram struct stores the bins of a histogram encoded in little endian
+// (e.g. a histogram of a memory-mapped file), so it can be compared without
+// decoding it. The Type can be Float64, Float32 or Uint16 (each count is
+// multiplied by the Scale).
+type EncodedHistogram struct {
+	Type  Storage
+	Scale float64
+	Data  []byte
+}
+
+// Size function returns the number of bytes used by each bin of the storage.
+// It returns 0 if the storage does not have a fixed size (e.g. Sparse).
+func Size(storage Storage) int {
+	switch storage {
+	case Float64, "":
+		return 8
+	case Float32:
+		return 4
+	case Uint16:
+		return 2
+	}
+	return 0
+}
+
+// Storage function returns the representation of the histogram.
+func (hist EncodedHistogram) Storage() Storage { return hist.Type }
+
+// Len function returns the number of bins of the histogram.
+func (hist EncodedHistogram) Len() int {
+	if size := Size(hist.Type); size > 0 {
+		return len(hist.Data) / size
+	}
+	return 0
+}
+
+// at function decodes the bin at the index.
+func (hist EncodedHistogram) at(index int) float64 {
+	switch hist.Type {
+	case Float32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(hist.Data[index*4:])))
+	case Uint16:
+		return float64(binary.LittleEndian.Uint16(hist.Data[index*2:])) * hist.Scale
+	default:
+		return math.Float64frombits(binary.LittleEndian.Uint64(hist.Data[index*8:]))
+	}
+}
+
+// Float64s function returns the decoded bins of the histogram.
+func (hist EncodedHistogram) Float64s() []float64 {
+	decoded := make([]float64, hist.Len())
+	for index := range decoded {
+		decoded[index] = hist.at(index)
+	}
+	return decoded
+}
+
+func (hist EncodedHistogram) accumulate(query []float64, fn binFunc) float64 {
+	var sum float64
+	for index := 0; index < hist.Len(); index++ {
+		sum += fn(query[index], hist.at(index))
+	}
+	return sum
+}
+
+// Encode function encodes the histogram in little endian using the storage
+// (Float64, Float32 or Uint16) and appends it to the data. It returns the
+// data and the scale of the Uint16 storage.
+func Encode(data []byte, hist []float64, storage Storage) ([]byte, float64, error) {
+	converted, err := New(hist, storage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Grow the data by the size of the encoded histogram
+	start := len(data)
+	data = append(data, make([]byte, len(hist)*Size(storage))...)
+	encoded := data[start:]
+
+	scale := 1.0
+	switch converted := converted.(type) {
+	case Float64Histogram:
+		for index, value := range converted {
+			binary.LittleEndian.PutUint64(encoded[index*8:], math.Float64bits(value))
+		}
+	case Float32Histogram:
+		for index, value := range converted {
+			binary.LittleEndian.PutUint32(encoded[index*4:], math.Float32bits(value))
+		}
+	case Uint16Histogram:
+		for index, count := range converted.Counts {
+			binary.LittleEndian.PutUint16(encoded[index*2:], count)
+		}
+		scale = converted.Scale
+	default:
+		return nil, 0, errEncodedStorage
+	}
+	return data, scale, nil
+}
@@ -0,0 +1,334 @@
+package lbph
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"image"
+	"io"
+	"sync"
+
+	"github.com/kelvins/lbph/compact"
+	"github.com/kelvins/lbph/metric"
+)
+
+// galleryMagic identifies the gallery files.
+var galleryMagic = []byte("LBPG")
+
+// galleryVersion is the current version of the gallery files.
+// Version 2 added the sample records and version 3 the mask.
+const galleryVersion uint16 = 3
+
+// galleryParams maps each gallery version to the format of its LBPH parameters
+// (the model version passed to readParams), so the parameters of the old galleries
+// can still be read if the model format changes.
+var galleryParams = map[uint16]uint16{1: 2, 2: 2, 3: 2}
+
+// galleryAlignment is the alignment (in bytes) of the features section.
+const galleryAlignment = 64
+
+// errGalleryClosed is returned when a closed gallery is used.
+var errGalleryClosed = errors.New("The gallery is closed")
+
+// Gallery struct is a gallery file opened by the OpenGallery function. The header
+// (parameters, metric, labels, samples and offsets) is decoded and the features section
+// (one histogram per label, with a fixed stride) is memory-mapped read-only, so
+// the histograms are not loaded into the Go heap. Use the UseGallery function to
+// search it using the Predict functions. The mutex guards the mapped data, so it
+// is not unmapped (Close) while it is read.
+type Gallery struct {
+	mu      sync.RWMutex
+	data    []byte
+	params  Params
+	metric  string
+	width   int
+	height  int
+	labels  []string
+	samples []Sample
+	mask    image.Image
+	scales  []float64
+	storage compact.Storage
+	bins    int
+	stride  int
+	offset  int
+	closed  bool
+}
+
+// SaveGallery function writes the trained model as a gallery file: a small header
+// (LBPH parameters, metric, labels, samples, mask and offsets, protected by a checksum) followed by
+// the features section, where each histogram is stored in little endian with a fixed
+// stride (aligned to 64 bytes), so it can be memory-mapped by the OpenGallery function.
+// The histograms are stored as float32 or uint16 if the Storage parameter is
+// compact.Float32 or compact.Uint16 (the Sparse storage is stored as float32),
+// otherwise as float64. The fitted metrics, the prototypes and the calibration are not
+// stored, so the models using them are not supported.
+func SaveGallery(w io.Writer) error {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return errors.New("The algorithm was not trained yet")
+	}
+	if trainingData.SelectedMetric == nil {
+		return errors.New("The metric is nil")
+	}
+	if trainingData.Metric != nil || trainingData.Calibration != nil || len(trainingData.PrototypeHistograms) > 0 {
+		return errors.New("The gallery files do not support the fitted metrics, the calibration or the prototypes")
+	}
+
+	storage := compact.Float64
+	switch trainingData.Params.Storage {
+	case compact.Float32, compact.Sparse:
+		storage = compact.Float32
+	case compact.Uint16:
+		storage = compact.Uint16
+	}
+
+	// Encode the features section
+	histograms, err := trainingData.getHistograms()
+	if err != nil {
+		return err
+	}
+	bins := len(histograms[0])
+	var features []byte
+	var scales []float64
+	for _, hist := range histograms {
+		var scale float64
+		var err error
+		if features, scale, err = compact.Encode(features, hist, storage); err != nil {
+			return err
+		}
+		if storage == compact.Uint16 {
+			scales = append(scales, scale)
+		}
+	}
+	stride := bins * compact.Size(storage)
+
+	// Encode the header (the offset of the features depends on the header size)
+	var mask []byte
+	if trainingData.Mask != nil {
+		if mask, err = encodeImage(trainingData.Mask); err != nil {
+			return err
+		}
+	}
+	var header encoder
+	header.writeParams(trainingData.Params)
+	header.writeString(trainingData.SelectedMetric.Name())
+	header.writeUint32(uint32(trainingData.Width))
+	header.writeUint32(uint32(trainingData.Height))
+	header.writeString(string(storage))
+	header.writeUint32(uint32(bins))
+	header.writeUint32(uint32(stride))
+	header.writeStrings(trainingData.Labels)
+	header.writeFloat64s(scales)
+
+	// Sample records (added in the version 2)
+	if trainingData.Samples != nil {
+		header.writeUint8(1)
+		header.writeSamples(trainingData.Samples)
+	} else {
+		header.writeUint8(0)
+	}
+
+	// Mask used to train the algorithm (added in the version 3, empty if it is nil)
+	header.writeBytes(mask)
+
+	// magic + version + header size + header + checksum
+	headerEnd := len(galleryMagic) + 2 + 4 + header.buf.Len() + 4
+	offset := (headerEnd + galleryAlignment - 1) / galleryAlignment * galleryAlignment
+
+	var e encoder
+	e.buf.Write(galleryMagic)
+	e.writeUint16(galleryVersion)
+	e.writeBytes(header.buf.Bytes())
+	e.writeUint32(crc32.ChecksumIEEE(e.buf.Bytes()))
+	e.buf.Write(make([]byte, offset-headerEnd))
+
+	if _, err := w.Write(e.buf.Bytes()); err != nil {
+		return err
+	}
+	_, err = w.Write(features)
+	return err
+}
+
+// OpenGallery function opens a gallery file written by the SaveGallery function.
+// The features section is memory-mapped read-only (on the systems that support it,
+// otherwise the file is read). The gallery should be closed using the Close function.
+func OpenGallery(path string) (*Gallery, error) {
+	data, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gallery, err := newGallery(data)
+	if err != nil {
+		unmapFile(data)
+		return nil, err
+	}
+	return gallery, nil
+}
+
+// newGallery function decodes the header of the gallery file.
+func newGallery(data []byte) (*Gallery, error) {
+	if len(data) < len(galleryMagic)+2+4+4 || !bytes.Equal(data[:len(galleryMagic)], galleryMagic) {
+		return nil, errors.New("The data is not a LBPH gallery")
+	}
+
+	d := decoder{data: data[len(galleryMagic):]}
+	version := d.readUint16()
+	header := decoder{data: d.readBytes()}
+	checksum := d.readUint32()
+	if d.err != nil {
+		return nil, d.err
+	}
+	headerEnd := len(data) - len(d.data)
+	if checksum != crc32.ChecksumIEEE(data[:headerEnd-4]) {
+		return nil, errors.New("The gallery checksum does not match (the gallery is corrupted)")
+	}
+	if _, ok := galleryParams[version]; !ok {
+		return nil, errors.New("The gallery version is not supported")
+	}
+
+	g := &Gallery{data: data}
+	g.params = header.readParams(galleryParams[version])
+	g.metric = header.readString()
+	g.width = int(header.readUint32())
+	g.height = int(header.readUint32())
+	g.storage = compact.Storage(header.readString())
+	g.bins = int(header.readUint32())
+	g.stride = int(header.readUint32())
+	g.labels = header.readStrings()
+	g.scales = header.readFloat64s()
+	if version >= 2 && header.readUint8() == 1 {
+		g.samples = header.readSamples(g.labels)
+	}
+	var mask []byte
+	if version >= 3 {
+		mask = header.readBytes()
+	}
+	if header.err != nil {
+		return nil, header.err
+	}
+	if len(mask) > 0 {
+		var err error
+		if g.mask, err = decodeImage(mask); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check the features section
+	g.offset = (headerEnd + galleryAlignment - 1) / galleryAlignment * galleryAlignment
+	size := compact.Size(g.storage)
+	if size == 0 || g.bins == 0 || g.stride != g.bins*size || len(g.labels) == 0 {
+		return nil, errors.New("The gallery features section is invalid")
+	}
+	if g.storage == compact.Uint16 && len(g.scales) != len(g.labels) {
+		return nil, errors.New("The gallery features section is invalid")
+	}
+	if g.offset+len(g.labels)*g.stride > len(data) {
+		return nil, errCorrupted
+	}
+
+	return g, nil
+}
+
+// Len function returns the number of histograms in the gallery.
+func (g *Gallery) Len() int {
+	return len(g.labels)
+}
+
+// Labels function returns the labels of the gallery.
+func (g *Gallery) Labels() []string {
+	return append([]string{}, g.labels...)
+}
+
+// Params function returns the LBPH parameters of the gallery.
+func (g *Gallery) Params() Params {
+	return g.params
+}
+
+// histogram function returns the histogram at the index (without decoding it).
+// The caller must hold the read lock and check that the gallery is not closed.
+func (g *Gallery) histogram(index int) compact.EncodedHistogram {
+	start := g.offset + index*g.stride
+	hist := compact.EncodedHistogram{Type: g.storage, Scale: 1, Data: g.data[start : start+g.stride]}
+	if g.storage == compact.Uint16 {
+		hist.Scale = g.scales[index]
+	}
+	return hist
+}
+
+// distance function compares the histogram to the histogram of the gallery at the
+// index (see the compact.Distance function).
+func (g *Gallery) distance(hist []float64, index int, selectedMetric metric.Metric) (float64, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.closed {
+		return 0, errGalleryClosed
+	}
+	return compact.Distance(hist, g.histogram(index), selectedMetric)
+}
+
+// histograms function decodes all histograms of the gallery.
+func (g *Gallery) histograms() ([][]float64, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.closed {
+		return nil, errGalleryClosed
+	}
+	histograms := make([][]float64, len(g.labels))
+	for index := range histograms {
+		histograms[index] = g.histogram(index).Float64s()
+	}
+	return histograms, nil
+}
+
+// Close function unmaps the gallery file. It waits for the Predict functions that
+// are reading the histograms, so it can be called concurrently with them. If the
+// gallery is used by the Predict functions (UseGallery), they return an error after
+// it is closed (the algorithm should be trained or loaded again).
+func (g *Gallery) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	data := g.data
+	g.data = nil
+	return unmapFile(data)
+}
+
+// UseGallery function replaces the training data by the gallery, so the Predict
+// functions search the memory-mapped histograms directly. It also sets the LBPH
+// parameters, the Metric and the Mask of the gallery (as the Load function). The
+// functions that change the training data (e.g. Update) decode the histograms into
+// the Go heap.
+func UseGallery(g *Gallery) error {
+	if g == nil {
+		return errors.New("The gallery is nil or closed")
+	}
+	g.mu.RLock()
+	closed := g.closed
+	g.mu.RUnlock()
+	if closed {
+		return errors.New("The gallery is nil or closed")
+	}
+	if _, err := metric.Lookup(g.metric); err != nil {
+		return errors.New("The metric of the gallery is not registered")
+	}
+
+	lbphParams = g.params
+	Metric = metric.Name(g.metric)
+	Mask = g.mask
+	trainingData = &TrainingData{
+		Labels:         g.labels,
+		Samples:        g.samples,
+		Params:         g.params,
+		Width:          g.width,
+		Height:         g.height,
+		SelectedMetric: metric.Name(g.metric),
+		Mask:           g.mask,
+		gallery:        g,
+	}
+	return nil
+}
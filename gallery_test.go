@@ -0,0 +1,224 @@
+package lbph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/kelvins/lbph/compact"
+	"github.com/kelvins/lbph/lbp"
+	"github.com/kelvins/lbph/mask"
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGallery(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+	}()
+	Metric = metric.ChiSquare
+	dir, err := ioutil.TempDir("", "lbph")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gallery.lbpg")
+
+	// The algorithm was not trained yet
+	Init(Params{})
+	var buf bytes.Buffer
+	assert.NotNil(t, SaveGallery(&buf))
+
+	images := trainSamples(t)
+	labels := GetTrainingData().Labels
+
+	for _, storage := range []compact.Storage{"", compact.Float32, compact.Uint16, compact.Sparse} {
+		Init(Params{Storage: storage})
+		err := Train(images, labels)
+		assert.Nil(t, err)
+		expected := GetTrainingData()
+		assert.Equal(t, storage, expected.Params.Storage)
+
+		var predictions []Prediction
+		for _, img := range images {
+			prediction, err := PredictTopK(img, 1)
+			assert.Nil(t, err)
+			predictions = append(predictions, prediction[0])
+		}
+
+		file, err := os.Create(path)
+		assert.Nil(t, err)
+		assert.Nil(t, SaveGallery(file))
+		assert.Nil(t, file.Close())
+
+		gallery, err := OpenGallery(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, gallery.Len())
+		assert.Equal(t, expected.Labels, gallery.Labels())
+		assert.Equal(t, expected.Params, gallery.Params())
+
+		// The Predict functions search the gallery
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+		assert.Nil(t, UseGallery(gallery))
+		assert.Equal(t, metric.ChiSquare, Metric)
+		assert.Nil(t, GetTrainingData().Histograms)
+
+		for index, img := range images {
+			prediction, err := PredictTopK(img, 1)
+			assert.Nil(t, err)
+			assert.Equal(t, predictions[index].Label, prediction[0].Label)
+			assert.InDelta(t, predictions[index].Distance, prediction[0].Distance, 1e-3)
+		}
+		results, err := PredictBatch(images)
+		assert.Nil(t, err)
+		assert.Equal(t, "rocks", results[0].Label)
+
+		// The model can be saved (the histograms are decoded)
+		buf.Reset()
+		assert.Nil(t, Save(&buf))
+
+		// The closed gallery can not be used
+		assert.Nil(t, gallery.Close())
+		assert.Nil(t, gallery.Close())
+		_, _, err = Predict(images[0])
+		assert.Equal(t, errGalleryClosed, err)
+		assert.Equal(t, errGalleryClosed, Update(images[:1], []string{"rocks"}))
+		assert.Equal(t, errGalleryClosed, Save(&bytes.Buffer{}))
+		assert.NotNil(t, UseGallery(gallery))
+
+		assert.Nil(t, Load(&buf))
+		label, _, err := Predict(images[0])
+		assert.Nil(t, err)
+		assert.Equal(t, "rocks", label)
+	}
+
+	// The sample records are stored in the header
+	var samples []Sample
+	for index, label := range labels {
+		samples = append(samples, Sample{ID: int64(index + 1), Label: label, Metadata: map[string]string{"source": "dataset"}})
+	}
+	assert.Nil(t, TrainSamples(images, samples))
+	buf.Reset()
+	assert.Nil(t, SaveGallery(&buf))
+	withSamples, err := newGallery(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, UseGallery(withSamples))
+	prediction, err := PredictTopK(images[1], 1)
+	assert.Nil(t, err)
+	assert.Equal(t, samples[1], prediction[0].Sample)
+
+	// The mask is stored in the header and set by UseGallery
+	Init(Params{})
+	width, height := lbp.GetImageSize(images[0])
+	Mask = mask.Ellipse(width, height)
+	assert.Nil(t, Train(images, labels))
+	buf.Reset()
+	assert.Nil(t, SaveGallery(&buf))
+	Mask = nil
+	masked, err := newGallery(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, UseGallery(masked))
+	assert.True(t, equalMasks(mask.Ellipse(width, height), Mask))
+	assert.True(t, equalMasks(Mask, GetTrainingData().Mask))
+	prediction, err = PredictTopK(images[0], 1)
+	assert.Nil(t, err)
+	assert.Equal(t, labels[0], prediction[0].Label)
+	assert.InDelta(t, 0, prediction[0].Distance, 1e-9)
+	Mask = nil
+
+	// The gallery can be updated (the histograms are decoded)
+	gallery, err := OpenGallery(path)
+	assert.Nil(t, err)
+	defer gallery.Close()
+	assert.Nil(t, UseGallery(gallery))
+	assert.Nil(t, Update(images[:1], []string{"rocks"}))
+	assert.Equal(t, 6, len(GetTrainingData().CompactHistograms))
+}
+
+func TestOpenGalleryInvalid(t *testing.T) {
+	defer func() { Metric = metric.EuclideanDistance }()
+	dir, err := ioutil.TempDir("", "lbph")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = OpenGallery(filepath.Join(dir, "missing.lbpg"))
+	assert.NotNil(t, err)
+
+	trainDataset(t)
+	var buf bytes.Buffer
+	assert.Nil(t, SaveGallery(&buf))
+	data := buf.Bytes()
+
+	var tTable = []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"magic", append([]byte("XXXX"), data[4:]...)},
+		{"header", append(append(append([]byte{}, data[:20]...), data[20]^0xFF), data[21:]...)},
+		{"features", data[:len(data)-8]},
+	}
+
+	for _, pair := range tTable {
+		path := filepath.Join(dir, pair.name)
+		assert.Nil(t, ioutil.WriteFile(path, pair.data, 0644))
+		_, err := OpenGallery(path)
+		assert.NotNil(t, err, pair.name)
+	}
+
+	// Unknown version (with a valid checksum)
+	unknown := append([]byte{}, data...)
+	binary.LittleEndian.PutUint16(unknown[4:], galleryVersion+1)
+	headerEnd := 4 + 2 + 4 + int(binary.LittleEndian.Uint32(unknown[6:])) + 4
+	binary.LittleEndian.PutUint32(unknown[headerEnd-4:], crc32.ChecksumIEEE(unknown[:headerEnd-4]))
+	_, err = newGallery(unknown)
+	assert.NotNil(t, err)
+	binary.LittleEndian.PutUint16(unknown[4:], galleryVersion)
+	binary.LittleEndian.PutUint32(unknown[headerEnd-4:], crc32.ChecksumIEEE(unknown[:headerEnd-4]))
+	_, err = newGallery(unknown)
+	assert.Nil(t, err)
+
+	// The fitted metrics are not supported
+	Metric = metric.MahalanobisDistance
+	trainDataset(t)
+	assert.NotNil(t, SaveGallery(&buf))
+}
+
+func TestGalleryCloseConcurrently(t *testing.T) {
+	defer Init(Params{})
+	dir, err := ioutil.TempDir("", "lbph")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gallery.lbpg")
+
+	images := trainSamples(t)
+	var buf bytes.Buffer
+	assert.Nil(t, SaveGallery(&buf))
+	assert.Nil(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+
+	gallery, err := OpenGallery(path)
+	assert.Nil(t, err)
+	assert.Nil(t, UseGallery(gallery))
+
+	// The Predict functions return an error once the gallery is closed
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if _, err := PredictTopK(images[0], 1); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	assert.Nil(t, gallery.Close())
+	wg.Wait()
+}
@@ -3,12 +3,27 @@ package histogram
 import (
 	"errors"
 
-	"github.com/kelvins/lbph/math"
 	"github.com/kelvins/lbph/metric"
 )
 
 // Calculate function generates a histogram based on the 'matrix' passed by parameter.
 func Calculate(pixels [][]uint64, gridX, gridY uint8) ([]float64, error) {
+	return CalculateWithMask(pixels, nil, gridX, gridY)
+}
+
+// CalculateWithMask function generates a histogram based on the 'matrix' passed by
+// parameter, weighting each pixel by the corresponding value of the mask. The mask
+// must have the same dimensions as the pixels 'matrix'. A weight of 0 means that the
+// pixel does not contribute to any region histogram, a weight of 1 means that it is
+// fully counted. If the mask is nil every pixel is counted once (same as Calculate).
+func CalculateWithMask(pixels [][]uint64, mask [][]float64, gridX, gridY uint8) ([]float64, error) {
+	return CalculateWithLayout(pixels, mask, NewGridLayout(gridX, gridY))
+}
+
+// CalculateWithLayout function generates a histogram based on the 'matrix' passed by
+// parameter, concatenating the histogram of each region defined by the layout.
+// The mask is optional (nil) and works as explained in the CalculateWithMask function.
+func CalculateWithLayout(pixels [][]uint64, mask [][]float64, layout Layout) ([]float64, error) {
 	var hist []float64
 
 	// Check the pixels 'matrix'
@@ -20,74 +35,89 @@ func Calculate(pixels [][]uint64, gridX, gridY uint8) ([]float64, error) {
 	rows := len(pixels)
 	cols := len(pixels[0])
 
-	// Check the grid (X and Y)
-	if gridX <= 0 || int(gridX) >= cols {
-		return hist, errors.New("Invalid grid X passed to the GetHistogram function")
+	// Check if the mask has the same dimensions as the pixels 'matrix'
+	if mask != nil {
+		if len(mask) != rows {
+			return hist, errors.New("The mask passed to the CalculateWithMask function has a different size")
+		}
+		for x := 0; x < rows; x++ {
+			if len(mask[x]) != len(pixels[x]) {
+				return hist, errors.New("The mask passed to the CalculateWithMask function has a different size")
+			}
+		}
 	}
-	if gridY <= 0 || int(gridX) >= rows {
-		return hist, errors.New("Invalid grid Y passed to the GetHistogram function")
+
+	// Get the regions positions (in pixels) based on the layout
+	rects, err := layout.rectangles(rows, cols)
+	if err != nil {
+		return hist, err
 	}
 
-	// Get the size (width and height) of each region
-	gridWidth := cols / int(gridX)
-	gridHeight := rows / int(gridY)
-
-	// Calculates the histogram of each grid
-	for gX := 0; gX < int(gridX); gX++ {
-		for gY := 0; gY < int(gridY); gY++ {
-			// Create a slice with empty 256 positions
-			regionHistogram := make([]float64, 256)
-
-			// Define the start and end positions for the following loop
-			startPosX := gX * gridWidth
-			startPosY := gY * gridHeight
-			endPosX := (gX + 1) * gridWidth
-			endPosY := (gY + 1) * gridHeight
-
-			// Make sure that no pixel has been leave at the end
-			if gX == int(gridX)-1 {
-				endPosX = cols
-			}
-			if gY == int(gridY)-1 {
-				endPosY = rows
-			}
+	// Calculates the histogram of each region
+	for _, rect := range rects {
+		// Create a slice with empty 256 positions
+		regionHistogram := make([]float64, 256)
 
-			// Creates the histogram for the current region
-			for x := startPosX; x < endPosX; x++ {
-				for y := startPosY; y < endPosY; y++ {
-					// Make sure we are trying to access a valid position
-					if x < len(pixels) {
-						if y < len(pixels[x]) {
-							if int(pixels[x][y]) < len(regionHistogram) {
+		// Creates the histogram for the current region
+		for x := rect.startX; x < rect.endX; x++ {
+			for y := rect.startY; y < rect.endY; y++ {
+				// Make sure we are trying to access a valid position
+				if x < len(pixels) {
+					if y < len(pixels[x]) {
+						if int(pixels[x][y]) < len(regionHistogram) {
+							// Masked out pixels (weight 0) are ignored
+							if mask == nil {
 								regionHistogram[pixels[x][y]] += 1
+							} else {
+								regionHistogram[pixels[x][y]] += mask[x][y]
 							}
 						}
 					}
 				}
 			}
-			// Concatenate two slices
-			hist = append(hist, regionHistogram...)
 		}
+		// Concatenate two slices
+		hist = append(hist, regionHistogram...)
 	}
 
 	return hist, nil
 }
 
+// Normalize function returns a copy of the histogram where each region histogram
+// (256 bins) is normalized, so its bins sum to 1. Empty regions are not changed.
+func Normalize(hist []float64) []float64 {
+	normalized := make([]float64, len(hist))
+
+	for start := 0; start < len(hist); start += 256 {
+		end := start + 256
+		if end > len(hist) {
+			end = len(hist)
+		}
+
+		var total float64
+		for index := start; index < end; index++ {
+			total += hist[index]
+		}
+		for index := start; index < end; index++ {
+			if total == 0 {
+				normalized[index] = hist[index]
+			} else {
+				normalized[index] = hist[index] / total
+			}
+		}
+	}
+
+	return normalized
+}
+
 // Compare function is used to compare two histograms using a selected metric.
+// Any implementation of the metric.Metric interface can be used, including the
+// names of the registered metrics (e.g. metric.EuclideanDistance).
 // Histogram comparison references:
 // http://docs.opencv.org/2.4/doc/tutorials/imgproc/histograms/histogram_comparison/histogram_comparison.html
-func Compare(hist1, hist2 []float64, selectedMetric string) (float64, error) {
-
-	switch selectedMetric {
-	case metric.ChiSquare:
-		return math.ChiSquare(hist1, hist2)
-	case metric.EuclideanDistance:
-		return math.EuclideanDistance(hist1, hist2)
-	case metric.NormalizedEuclideanDistance:
-		return math.NormalizedEuclideanDistance(hist1, hist2)
-	case metric.AbsoluteValue:
-		return math.AbsoluteValue(hist1, hist2)
+func Compare(hist1, hist2 []float64, selectedMetric metric.Metric) (float64, error) {
+	if selectedMetric == nil {
+		return 0, errors.New("Invalid metric selected to compare the histograms")
 	}
-
-	return 0, errors.New("Invalid metric selected to compare the histograms")
+	return selectedMetric.Distance(hist1, hist2)
 }
@@ -1,6 +1,7 @@
 package histogram
 
 import (
+	"math"
 	"testing"
 
 	"github.com/kelvins/lbph/metric"
@@ -52,6 +53,51 @@ func TestCalculate(t *testing.T) {
 	assert.Equal(t, expectedHist, hist, "The histograms should be equal")
 }
 
+func TestCalculateWithMask(t *testing.T) {
+	row1 := []uint64{255, 255, 255, 255}
+	row2 := []uint64{0, 0, 0, 0}
+	pixels := [][]uint64{row1, row2, row2, row1}
+
+	// Binary mask ignoring the first and last columns
+	binaryRow := []float64{0, 1, 1, 0}
+	mask := [][]float64{binaryRow, binaryRow, binaryRow, binaryRow}
+
+	expectedHist := make([]float64, 256)
+	expectedHist[0] = 4
+	expectedHist[255] = 4
+
+	hist, err := CalculateWithMask(pixels, mask, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedHist, hist, "The histograms should be equal")
+
+	// Weighted mask
+	weightedRow := []float64{0.5, 0.5, 0.5, 0.5}
+	mask = [][]float64{weightedRow, weightedRow, weightedRow, weightedRow}
+
+	expectedHist = make([]float64, 256)
+	expectedHist[0] = 4
+	expectedHist[255] = 4
+
+	hist, err = CalculateWithMask(pixels, mask, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedHist, hist, "The histograms should be equal")
+
+	// A nil mask should count every pixel
+	hist, err = CalculateWithMask(pixels, nil, 1, 1)
+	assert.Nil(t, err)
+	expectedHist[0] = 8
+	expectedHist[255] = 8
+	assert.Equal(t, expectedHist, hist, "The histograms should be equal")
+
+	// Masks with different sizes
+	_, err = CalculateWithMask(pixels, mask[:3], 1, 1)
+	assert.NotNil(t, err)
+
+	mask[1] = []float64{1, 1}
+	_, err = CalculateWithMask(pixels, mask, 1, 1)
+	assert.NotNil(t, err)
+}
+
 func TestCompare(t *testing.T) {
 	var hist1 []float64
 	var hist2 []float64
@@ -75,3 +121,102 @@ func TestCompare(t *testing.T) {
 	distance, _ = Compare(hist1, hist2, metric.EuclideanDistance)
 	assert.Equal(t, 10.0, distance, "The distance should be equal to 10")
 }
+
+func TestCalculateWithLayout(t *testing.T) {
+	row1 := []uint64{1, 1, 2, 2}
+	row2 := []uint64{3, 3, 4, 4}
+	pixels := [][]uint64{row1, row1, row2, row2}
+
+	// The grid layout should be equal to the Calculate function
+	expectedHist, err := Calculate(pixels, 2, 2)
+	assert.Nil(t, err)
+
+	hist, err := CalculateWithLayout(pixels, nil, NewGridLayout(2, 2))
+	assert.Nil(t, err)
+	assert.Equal(t, expectedHist, hist, "The histograms should be equal")
+
+	// Non-uniform regions
+	regions := []Region{
+		{X: 0, Y: 0, Width: 0.5, Height: 1},
+		{X: 0.5, Y: 0.5, Width: 0.5, Height: 0.5},
+	}
+
+	expectedHist = make([]float64, 512)
+	expectedHist[1] = 4
+	expectedHist[2] = 4
+	expectedHist[256+4] = 4
+
+	hist, err = CalculateWithLayout(pixels, nil, NewRegionLayout(regions))
+	assert.Nil(t, err)
+	assert.Equal(t, expectedHist, hist, "The histograms should be equal")
+
+	// Non-uniform grid
+	regions = GridRegions([]float64{0, 0.25, 1}, []float64{0, 1})
+	assert.Equal(t, 2, NewRegionLayout(regions).Size())
+
+	expectedHist = make([]float64, 512)
+	expectedHist[1] = 2
+	expectedHist[2] = 2
+	expectedHist[256+1] = 2
+	expectedHist[256+2] = 2
+	expectedHist[256+3] = 4
+	expectedHist[256+4] = 4
+
+	hist, err = CalculateWithLayout(pixels, nil, NewRegionLayout(regions))
+	assert.Nil(t, err)
+	assert.Equal(t, expectedHist, hist, "The histograms should be equal")
+
+	// Invalid regions
+	_, err = CalculateWithLayout(pixels, nil, NewRegionLayout([]Region{{X: 0.5, Y: 0, Width: 0.6, Height: 1}}))
+	assert.NotNil(t, err)
+
+	_, err = CalculateWithLayout(pixels, nil, NewRegionLayout([]Region{{X: 0, Y: 0, Width: 0, Height: 1}}))
+	assert.NotNil(t, err)
+}
+
+func TestCompareCustomMetric(t *testing.T) {
+	hist1 := []float64{1, 2, 3}
+	hist2 := []float64{3, 2, 1}
+
+	_, err := Compare(hist1, hist2, nil)
+	assert.NotNil(t, err)
+
+	_, err = Compare(hist1, hist2, metric.Name("InvalidMetric"))
+	assert.NotNil(t, err)
+
+	custom := metric.New("Max", func(hist1, hist2 []float64) (float64, error) {
+		return 42, nil
+	})
+	distance, err := Compare(hist1, hist2, custom)
+	assert.Nil(t, err)
+	assert.Equal(t, 42.0, distance)
+}
+
+func TestCompareChiSquare(t *testing.T) {
+	// Sparse histograms (empty bins) should not return +Inf or NaN
+	hist1 := []float64{0, 4, 0, 2}
+	hist2 := []float64{0, 0, 1, 2}
+
+	distance, err := Compare(hist1, hist2, metric.ChiSquare)
+	assert.Nil(t, err)
+	assert.Equal(t, 5.0, distance)
+
+	// The asymmetric chi square divides by the empty bins of hist1
+	// (the bins empty in both histograms are skipped)
+	distance, err = Compare(hist1, hist2, metric.AsymmetricChiSquare)
+	assert.Nil(t, err)
+	assert.True(t, math.IsInf(distance, 1))
+}
+
+func TestNormalize(t *testing.T) {
+	hist := make([]float64, 512)
+	hist[0] = 1
+	hist[255] = 3
+
+	expectedHist := make([]float64, 512)
+	expectedHist[0] = 0.25
+	expectedHist[255] = 0.75
+
+	assert.Equal(t, expectedHist, Normalize(hist), "The histograms should be equal")
+	assert.Equal(t, 1.0, hist[0], "The histogram passed by parameter should not change")
+}
@@ -0,0 +1,132 @@
+package histogram
+
+import (
+	"errors"
+)
+
+// Region struct defines a rectangle in normalized coordinates ([0, 1]) from which
+// a histogram is calculated. X and Width are relative to the image width and
+// Y and Height are relative to the image height.
+type Region struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Layout struct defines the regions used to calculate the histograms.
+// If the Regions slice is empty an uniform grid (GridX x GridY) is used,
+// otherwise a histogram is calculated for each region (in the same order).
+type Layout struct {
+	GridX   uint8    `json:"grid_x,omitempty"`
+	GridY   uint8    `json:"grid_y,omitempty"`
+	Regions []Region `json:"regions,omitempty"`
+}
+
+// rectangle struct stores the region positions in pixels.
+type rectangle struct {
+	startX, startY, endX, endY int
+}
+
+// NewGridLayout function returns an uniform grid layout (GridX x GridY).
+func NewGridLayout(gridX, gridY uint8) Layout {
+	return Layout{GridX: gridX, GridY: gridY}
+}
+
+// NewRegionLayout function returns a layout based on a list of arbitrary regions
+// (e.g. aligned on facial landmarks).
+func NewRegionLayout(regions []Region) Layout {
+	return Layout{Regions: regions}
+}
+
+// GridRegions function returns the regions of a non-uniform grid based on the
+// edges passed by parameter. The edges are normalized coordinates in ascending
+// order, e.g. []float64{0, 0.2, 0.8, 1} defines 3 columns (or rows).
+func GridRegions(xEdges, yEdges []float64) []Region {
+	var regions []Region
+
+	for gX := 0; gX < len(xEdges)-1; gX++ {
+		for gY := 0; gY < len(yEdges)-1; gY++ {
+			regions = append(regions, Region{
+				X:      xEdges[gX],
+				Y:      yEdges[gY],
+				Width:  xEdges[gX+1] - xEdges[gX],
+				Height: yEdges[gY+1] - yEdges[gY],
+			})
+		}
+	}
+
+	return regions
+}
+
+// IsGrid function checks if the layout is an uniform grid.
+func (layout Layout) IsGrid() bool {
+	return len(layout.Regions) == 0
+}
+
+// Size function returns the number of regions (histograms) in the layout.
+func (layout Layout) Size() int {
+	if layout.IsGrid() {
+		return int(layout.GridX) * int(layout.GridY)
+	}
+	return len(layout.Regions)
+}
+
+// rectangles function converts the layout to rectangles (in pixels) based on
+// the 'matrix' dimensions. The rows are the first index of the 'matrix' (x).
+func (layout Layout) rectangles(rows, cols int) ([]rectangle, error) {
+	var rects []rectangle
+
+	if layout.IsGrid() {
+		// Check the grid (X and Y)
+		if layout.GridX <= 0 || int(layout.GridX) >= cols {
+			return rects, errors.New("Invalid grid X passed to the GetHistogram function")
+		}
+		if layout.GridY <= 0 || int(layout.GridX) >= rows {
+			return rects, errors.New("Invalid grid Y passed to the GetHistogram function")
+		}
+
+		// Get the size (width and height) of each region
+		gridWidth := cols / int(layout.GridX)
+		gridHeight := rows / int(layout.GridY)
+
+		for gX := 0; gX < int(layout.GridX); gX++ {
+			for gY := 0; gY < int(layout.GridY); gY++ {
+				// Define the start and end positions
+				rect := rectangle{
+					startX: gX * gridWidth,
+					startY: gY * gridHeight,
+					endX:   (gX + 1) * gridWidth,
+					endY:   (gY + 1) * gridHeight,
+				}
+
+				// Make sure that no pixel has been leave at the end
+				if gX == int(layout.GridX)-1 {
+					rect.endX = cols
+				}
+				if gY == int(layout.GridY)-1 {
+					rect.endY = rows
+				}
+
+				rects = append(rects, rect)
+			}
+		}
+		return rects, nil
+	}
+
+	for _, region := range layout.Regions {
+		// Check if the region is inside the image
+		if region.X < 0 || region.Y < 0 || region.Width <= 0 || region.Height <= 0 ||
+			region.X+region.Width > 1 || region.Y+region.Height > 1 {
+			return rects, errors.New("Invalid region passed to the GetHistogram function")
+		}
+
+		rects = append(rects, rectangle{
+			startX: int(region.X * float64(rows)),
+			startY: int(region.Y * float64(cols)),
+			endX:   int((region.X + region.Width) * float64(rows)),
+			endY:   int((region.Y + region.Height) * float64(cols)),
+		})
+	}
+	return rects, nil
+}
@@ -0,0 +1,100 @@
+package lbph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"image"
+	"io"
+)
+
+// featureMagic identifies the binary feature format.
+var featureMagic = []byte("LBPF")
+
+// Feature struct is a single feature vector (the histogram of an image) with
+// the LBPH parameters used to calculate it and an optional label. It can be
+// encoded using JSON (encoding/json) or the binary format (MarshalBinary).
+type Feature struct {
+	Label     string    `json:"label,omitempty"`
+	Params    Params    `json:"params"`
+	Histogram []float64 `json:"histogram"`
+}
+
+// ExtractFeature function calculates the feature vector of the image using the
+// current LBPH parameters and Mask. The algorithm does not need to be trained.
+func ExtractFeature(img image.Image, label string) (Feature, error) {
+	if img == nil {
+		return Feature{}, errors.New("The image passed by parameter is nil")
+	}
+
+	hist, err := extractHistogram(img, getMaskWeights())
+	if err != nil {
+		return Feature{}, err
+	}
+
+	return Feature{Label: label, Params: lbphParams, Histogram: hist}, nil
+}
+
+// MarshalBinary function encodes the feature using a binary format with a checksum.
+func (f Feature) MarshalBinary() ([]byte, error) {
+	var e encoder
+	e.buf.Write(featureMagic)
+	e.writeUint16(modelVersion)
+	e.writeParams(f.Params)
+	e.writeString(f.Label)
+	e.writeFloat64s(f.Histogram)
+	e.writeUint32(crc32.ChecksumIEEE(e.buf.Bytes()))
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalBinary function decodes a feature encoded by the MarshalBinary function.
+func (f *Feature) UnmarshalBinary(data []byte) error {
+	if len(data) < len(featureMagic)+2+4 || !bytes.Equal(data[:len(featureMagic)], featureMagic) {
+		return errors.New("The data is not a LBPH feature")
+	}
+	content := data[:len(data)-4]
+	d := decoder{data: data[len(data)-4:]}
+	if d.readUint32() != crc32.ChecksumIEEE(content) {
+		return errors.New("The feature checksum does not match (the feature is corrupted)")
+	}
+
+	d = decoder{data: content[len(featureMagic):]}
+	version := d.readUint16()
+	if version == 0 || version > modelVersion {
+		return errors.New("The feature version is not supported")
+	}
+	feature := Feature{Params: d.readParams(version), Label: d.readString(), Histogram: d.readFloat64s()}
+	if d.err != nil {
+		return d.err
+	}
+
+	*f = feature
+	return nil
+}
+
+// SaveJSON function writes the trained model to the writer using a human-readable
+// JSON format. It stores the same data as the Save function (the images and the mask
+// are encoded as base64 PNG, the compact histograms are decoded), so the models can
+// be converted between both formats.
+func SaveJSON(w io.Writer) error {
+	m, err := newModel(trainingData)
+	if err != nil {
+		return err
+	}
+	m.decodeHistograms()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// LoadJSON function reads a model written by the SaveJSON function and replaces the
+// training data, the LBPH parameters, the Metric and the Mask (same as Load).
+func LoadJSON(r io.Reader) error {
+	var m model
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return err
+	}
+	return m.apply()
+}
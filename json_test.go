@@ -0,0 +1,139 @@
+package lbph
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"image"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// updateGolden flag is used to update the golden files (go test -update).
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// checkGolden function compares the data with the golden file.
+func checkGolden(t *testing.T, path string, data []byte) {
+	if *updateGolden {
+		assert.Nil(t, ioutil.WriteFile(path, data, 0644))
+	}
+	golden, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, string(golden), string(data))
+}
+
+// trainGolden function trains a small model (one region per image) used by the golden files.
+func trainGolden(t *testing.T) []image.Image {
+	Init(Params{GridX: 1, GridY: 1})
+	Metric = metric.ChiSquare
+
+	paths := []string{"./dataset/train/1.png", "./dataset/train/2.png", "./dataset/train/3.png"}
+	labels := []string{"rocks", "grass", "wood"}
+
+	var images []image.Image
+	for index := 0; index < len(paths); index++ {
+		img, err := LoadImage(paths[index])
+		assert.Nil(t, err)
+		images = append(images, img)
+	}
+
+	err := Train(images, labels)
+	assert.Nil(t, err)
+	return images
+}
+
+func TestSaveLoadJSON(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+	}()
+
+	// The algorithm was not trained yet
+	Init(Params{})
+	var buf bytes.Buffer
+	assert.NotNil(t, SaveJSON(&buf))
+
+	images := trainGolden(t)
+	var binaryModel bytes.Buffer
+	assert.Nil(t, Save(&binaryModel))
+
+	assert.Nil(t, SaveJSON(&buf))
+	checkGolden(t, "./testdata/model.json", buf.Bytes())
+
+	// JSON -> binary -> JSON should not change the model
+	Init(Params{})
+	Metric = metric.EuclideanDistance
+	assert.Nil(t, LoadJSON(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, metric.ChiSquare, Metric)
+
+	var converted bytes.Buffer
+	assert.Nil(t, Save(&converted))
+	assert.Equal(t, binaryModel.Bytes(), converted.Bytes())
+
+	Init(Params{})
+	assert.Nil(t, Load(&converted))
+	var roundTrip bytes.Buffer
+	assert.Nil(t, SaveJSON(&roundTrip))
+	assert.Equal(t, buf.String(), roundTrip.String())
+
+	label, _, err := Predict(images[2])
+	assert.Nil(t, err)
+	assert.Equal(t, "wood", label)
+}
+
+func TestLoadJSONInvalid(t *testing.T) {
+	var tTable = []string{
+		``,
+		`{"version": 2`,
+		`{"version": 3, "metric": "ChiSquare", "labels": ["a"], "histograms": [[1]]}`,
+		`{"version": 2, "metric": "ChiSquare", "labels": ["a", "b"], "histograms": [[1]]}`,
+		`{"version": 2, "metric": "ChiSquare", "labels": ["a", "b"], "histograms": [[1], [1, 2]]}`,
+		`{"version": 2, "metric": "Invalid", "labels": ["a"], "histograms": [[1]]}`,
+		`{"version": 2, "metric": "ChiSquare", "labels": ["a"], "histograms": [[1]], "calibration": {"type": "Invalid"}}`,
+	}
+
+	for _, data := range tTable {
+		err := LoadJSON(strings.NewReader(data))
+		assert.NotNil(t, err, data)
+	}
+}
+
+func TestFeature(t *testing.T) {
+	defer Init(Params{})
+	Init(Params{GridX: 1, GridY: 1})
+
+	_, err := ExtractFeature(nil, "")
+	assert.NotNil(t, err)
+
+	img, err := LoadImage("./dataset/test/1.png")
+	assert.Nil(t, err)
+
+	feature, err := ExtractFeature(img, "wood")
+	assert.Nil(t, err)
+	assert.Equal(t, 256, len(feature.Histogram))
+
+	data, err := json.MarshalIndent(feature, "", "  ")
+	assert.Nil(t, err)
+	checkGolden(t, "./testdata/feature.json", append(data, '\n'))
+
+	var decoded Feature
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, feature, decoded)
+
+	// Binary format
+	data, err = feature.MarshalBinary()
+	assert.Nil(t, err)
+
+	decoded = Feature{}
+	assert.Nil(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, feature, decoded)
+
+	data[len(data)/2] ^= 0xFF
+	assert.NotNil(t, decoded.UnmarshalBinary(data))
+	assert.NotNil(t, decoded.UnmarshalBinary(data[:3]))
+}
@@ -0,0 +1,136 @@
+package lbph
+
+import (
+	"errors"
+	"image"
+)
+
+// Voting type defines how the K nearest training images vote in the
+// k-nearest-neighbour (k-NN) decision mode.
+type Voting string
+
+// Voting modes used by the k-NN decision mode.
+// MajorityVoting gives one vote to each neighbor, DistanceWeightedVoting weights
+// each vote by the inverse of the distance and RankWeightedVoting weights each
+// vote by the rank (K votes to the closest neighbor and 1 vote to the farthest).
+const (
+	MajorityVoting         Voting = "Majority"
+	DistanceWeightedVoting Voting = "DistanceWeighted"
+	RankWeightedVoting     Voting = "RankWeighted"
+)
+
+// KNN struct is used to define the k-NN decision mode.
+type KNN struct {
+	K      int
+	Voting Voting
+}
+
+// Vote struct stores the result of the k-NN decision mode: the winning label,
+// its vote share (between 0 and 1) and the distance to the closest training
+// image with the winning label.
+type Vote struct {
+	Label    string
+	Share    float64
+	Distance float64
+}
+
+// Decision defines the k-NN decision mode used in the Predict step.
+// If Decision.K is lower or equal to 1 (default) the Predict function
+// returns the label of the closest image (1-NN).
+var Decision = KNN{K: 1, Voting: MajorityVoting}
+
+// zeroDistance is added to the distances by the DistanceWeightedVoting,
+// so an exact match (distance 0) does not divide by zero.
+const zeroDistance = 1e-12
+
+// getVoteWeight function returns the weight of the vote based on the voting mode,
+// the rank (0 for the closest neighbor) and the distance of the neighbor.
+func getVoteWeight(voting Voting, k, rank int, distance float64) (float64, error) {
+	switch voting {
+	case MajorityVoting, "":
+		return 1, nil
+	case DistanceWeightedVoting:
+		return 1 / (distance + zeroDistance), nil
+	case RankWeightedVoting:
+		return float64(k - rank), nil
+	}
+	return 0, errors.New("Invalid voting mode selected")
+}
+
+// vote function selects the winning label from the predictions (sorted by distance).
+// If two or more labels have the same votes, the label of the closest neighbor wins.
+// If the total weight is zero, each neighbor has one vote (MajorityVoting).
+func vote(predictions []Prediction, voting Voting) (Vote, error) {
+	var result Vote
+
+	if len(predictions) == 0 {
+		return result, errors.New("There are no predictions to vote")
+	}
+
+	// Sum the votes of each label
+	votes := make(map[string]float64)
+	var total float64
+	for rank, prediction := range predictions {
+		weight, err := getVoteWeight(voting, len(predictions), rank, prediction.Distance)
+		if err != nil {
+			return result, err
+		}
+		votes[prediction.Label] += weight
+		total += weight
+	}
+
+	// If all neighbors have no weight (e.g. infinite distances in the distance
+	// weighted voting), the unweighted votes are used to avoid a NaN share.
+	if total == 0 && voting != MajorityVoting {
+		return vote(predictions, MajorityVoting)
+	}
+
+	// As the predictions are sorted by distance, only a label with
+	// more votes replaces the winner (tie-breaking by the closest neighbor).
+	// The first prediction is the initial winner (its label can be empty).
+	for index, prediction := range predictions {
+		if index == 0 || votes[prediction.Label] > votes[result.Label] {
+			result = Vote{
+				Label:    prediction.Label,
+				Share:    votes[prediction.Label] / total,
+				Distance: prediction.Distance,
+			}
+		}
+	}
+	return result, nil
+}
+
+// PredictKNN function is used to classify the image passed by parameter using
+// the k-NN decision mode defined by the Decision variable. The K closest training
+// images vote for their labels and the label with more votes is returned.
+// If the distance to the closest image with the winning label is higher than
+// the threshold, it returns an empty label and the ErrUnknown error.
+func PredictKNN(img image.Image) (Vote, error) {
+	return predictKNN(img, getMaskWeights())
+}
+
+// predictKNN function classifies the image (see the PredictKNN function) using
+// the weights of the Mask passed by parameter.
+func predictKNN(img image.Image, weights *maskWeights) (Vote, error) {
+	k := Decision.K
+	if k < 1 {
+		k = 1
+	}
+
+	// Get the K closest training images.
+	predictions, err := predictTopK(img, k, weights)
+	if err != nil {
+		return Vote{}, err
+	}
+
+	result, err := vote(predictions, Decision.Voting)
+	if err != nil {
+		return result, err
+	}
+
+	// Check if the image is unknown (distance higher than the threshold).
+	if err := checkThreshold(result.Label, result.Distance); err != nil {
+		return Vote{Distance: result.Distance}, err
+	}
+	return result, nil
+}
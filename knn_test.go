@@ -0,0 +1,98 @@
+package lbph
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVote(t *testing.T) {
+	predictions := []Prediction{
+		{Label: "rocks", Distance: 1, Index: 0},
+		{Label: "wood", Distance: 2, Index: 1},
+		{Label: "wood", Distance: 3, Index: 2},
+		{Label: "rocks", Distance: 10, Index: 3},
+		{Label: "grass", Distance: 20, Index: 4},
+	}
+
+	// Table tests
+	var tTable = []struct {
+		voting Voting
+		k      int
+		label  string
+		share  float64
+	}{
+		// Tie-breaking by the closest neighbor
+		{MajorityVoting, 4, "rocks", 0.5},
+		{MajorityVoting, 3, "wood", 2.0 / 3.0},
+		{MajorityVoting, 5, "rocks", 0.4},
+		{RankWeightedVoting, 3, "rocks", 3.0 / 6.0},
+		{RankWeightedVoting, 4, "rocks", 5.0 / 10.0},
+		{RankWeightedVoting, 5, "rocks", 7.0 / 15.0},
+		{DistanceWeightedVoting, 3, "rocks", 1.0 / (1.0 + 1.0/2.0 + 1.0/3.0)},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		result, err := vote(predictions[:pair.k], pair.voting)
+		assert.Nil(t, err)
+		assert.Equal(t, pair.label, result.Label, "The labels should be equal")
+		assert.InDelta(t, pair.share, result.Share, 1e-9)
+	}
+
+	result, err := vote(predictions[:3], MajorityVoting)
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, result.Distance)
+
+	// An exact match should win the distance weighted voting
+	result, err = vote([]Prediction{{Label: "a", Distance: 0}, {Label: "b", Distance: 1}, {Label: "b", Distance: 1}}, DistanceWeightedVoting)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", result.Label)
+
+	// Infinite distances (e.g. disjoint histograms) fall back to the majority voting
+	infinite := []Prediction{
+		{Label: "a", Distance: math.Inf(1)}, {Label: "b", Distance: math.Inf(1)}, {Label: "b", Distance: math.Inf(1)},
+	}
+	result, err = vote(infinite, DistanceWeightedVoting)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", result.Label)
+	assert.InDelta(t, 2.0/3.0, result.Share, 1e-9)
+	assert.True(t, math.IsInf(result.Distance, 1))
+
+	// The empty label is a valid label (it wins the tie as the closest neighbor)
+	empty := []Prediction{{Label: "", Distance: 1}, {Label: "a", Distance: 2}, {Label: "", Distance: 3}, {Label: "a", Distance: 4}}
+	result, err = vote(empty, MajorityVoting)
+	assert.Nil(t, err)
+	assert.Equal(t, Vote{Label: "", Share: 0.5, Distance: 1}, result)
+
+	_, err = vote(predictions, Voting("Invalid"))
+	assert.NotNil(t, err)
+
+	_, err = vote(nil, MajorityVoting)
+	assert.NotNil(t, err)
+}
+
+func TestPredictKNN(t *testing.T) {
+	trainDataset(t)
+	defer func() { Decision = KNN{K: 1, Voting: MajorityVoting} }()
+
+	img, err := LoadImage("./dataset/test/1.png")
+	assert.Nil(t, err)
+
+	Decision = KNN{K: 3, Voting: RankWeightedVoting}
+	result, err := PredictKNN(img)
+	assert.Nil(t, err)
+	assert.Equal(t, "wood", result.Label)
+	assert.InDelta(t, 0.5, result.Share, 1e-9)
+
+	// The Predict function should use the k-NN decision mode
+	label, distance, err := Predict(img)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Label, label)
+	assert.Equal(t, result.Distance, distance)
+
+	Decision = KNN{K: 3, Voting: Voting("Invalid")}
+	_, _, err = Predict(img)
+	assert.NotNil(t, err)
+}
@@ -3,30 +3,75 @@ package lbph
 
 import (
 	"errors"
+	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"sort"
 
+	"github.com/kelvins/lbph/calibration"
+	"github.com/kelvins/lbph/compact"
 	"github.com/kelvins/lbph/histogram"
 	"github.com/kelvins/lbph/lbp"
+	"github.com/kelvins/lbph/mask"
 	"github.com/kelvins/lbph/metric"
 )
 
 // TrainingData struct is used to store the input data (images and labels)
-// and each calculated histogram.
+// and each calculated histogram. The Metric selected in the training step is
+// stored in SelectedMetric and, if it needs to be trained (e.g.
+// metric.MahalanobisDistance), the fitted metric is stored in Metric.
+// The Calibration is fitted by the Calibrate function. If the prototype
+// matching is enabled, the prototypes of each label are stored in the
+// PrototypeHistograms and PrototypeLabels slices and the prototype matching
+// mode selected in the training step is stored in Prototypes. The Mask used to
+// calculate the histograms is stored in Mask. The Params and the images
+// size (Width and Height) are used to check the images passed to Update.
+// The Images are only stored if RetainImages (Train) or RetainUpdateImages (the
+// other functions) is true, since they are not needed by the Predict step, and
+// they are nil for the loaded models. If a compact
+// storage is selected (Params.Storage), the histograms are stored in the
+// CompactHistograms slice and the Histograms slice is nil. If the algorithm
+// was trained using sample records (TrainSamples), the Samples slice stores
+// the record (ID, label and metadata) of each histogram.
 type TrainingData struct {
-	Images     []image.Image
-	Labels     []string
-	Histograms [][]float64
+	Images              []image.Image
+	Labels              []string
+	Samples             []Sample
+	Histograms          [][]float64
+	CompactHistograms   []compact.Histogram
+	Params              Params
+	Width               int
+	Height              int
+	SelectedMetric      metric.Metric
+	Metric              metric.Metric
+	Calibration         calibration.Calibrator
+	PrototypeHistograms [][]float64
+	PrototypeLabels     []string
+	Prototypes          PrototypeParams
+	Mask                image.Image
+	// gallery is the memory-mapped gallery (UseGallery) that stores the histograms.
+	gallery *Gallery
 }
 
 // Params struct is used to pass the LBPH parameters.
 type Params struct {
-	Radius    uint8
-	Neighbors uint8
-	GridX     uint8
-	GridY     uint8
+	Radius    uint8 `json:"radius"`
+	Neighbors uint8 `json:"neighbors"`
+	GridX     uint8 `json:"grid_x"`
+	GridY     uint8 `json:"grid_y"`
+	// Regions is an optional list of arbitrary regions (normalized coordinates)
+	// used instead of the uniform grid (GridX x GridY) to calculate the histograms.
+	Regions []histogram.Region `json:"regions,omitempty"`
+	// Normalize defines if each region histogram is normalized (the bins sum to 1),
+	// like the OpenCV LBPHFaceRecognizer. It makes the histograms independent of the
+	// regions size.
+	Normalize bool `json:"normalize,omitempty"`
+	// Storage defines the representation of the training histograms (e.g.
+	// compact.Float32, compact.Uint16 or compact.Sparse) used to reduce the
+	// memory of large galleries. Default value is compact.Float64 (empty).
+	Storage compact.Storage `json:"storage,omitempty"`
 }
 
 // trainData struct stores the TrainingData loaded by the user.
@@ -42,7 +87,30 @@ var trainingData = &TrainingData{}
 var lbphParams = Params{}
 
 // The metric used to compare the histograms in the Predict step.
-var Metric string
+// It can be one of the metric names (e.g. metric.ChiSquare) or any
+// custom implementation of the metric.Metric interface.
+var Metric metric.Metric
+
+// RetainImages defines if the Train function stores the training images in the
+// TrainingData. Only the histograms are needed by the Predict step, so it can be set
+// to false to reduce the memory used by large galleries. It is true by default to
+// keep the previous behavior. The models loaded by the Load functions only keep the
+// images if they were saved with SaveImages.
+var RetainImages = true
+
+// RetainUpdateImages defines if the TrainSamples, Update, UpdateSamples and Merge
+// functions store the training images in the TrainingData. It is false by default,
+// so the images are dropped unless the caller opts in. The images are only kept
+// if all training images are available (e.g. the images retained by Train are
+// dropped by Update if it is false).
+var RetainUpdateImages = false
+
+// Mask is an optional image (binary or weighted) used to ignore some regions of
+// the images when calculating the histograms (e.g. background, hair or mouth).
+// It must have the same size as the images and should be defined before calling
+// the Train function. Black pixels are ignored and white pixels are fully counted.
+// If it is nil (default) all pixels are used.
+var Mask image.Image
 
 // init define the default state of some variables.
 // It will set the default parameters for the LBPH,
@@ -96,56 +164,131 @@ func Init(params Params) {
 }
 
 // GetTrainingData is used to get the trainingData struct.
-// The user can use it to access the images, labels and histograms.
+// The user can use it to access the labels, histograms and images (if retained).
 func GetTrainingData() TrainingData {
 	// Returns the data structure pointed by trainData.
 	return *trainingData
 }
 
-// checkImagesSizes function is used to check if all images have the same size.
-func checkImagesSizes(images []image.Image) error {
-	// Check if the slice is empty
-	if len(images) == 0 {
-		return errors.New("The images slice is empty")
+// getImagesSize function returns the size of the first image that is not nil
+// (0 if all images are nil). All images of a batch must have this size.
+func getImagesSize(images []image.Image) (int, int) {
+	for _, img := range images {
+		if img != nil {
+			return lbp.GetImageSize(img)
+		}
+	}
+	return 0, 0
+}
+
+// checkImageSize function is used to check if the image is not nil and has
+// the size passed by parameter. It is called for each image of a batch.
+func checkImageSize(img image.Image, width, height int) error {
+	if img == nil {
+		return errors.New("The image is nil")
+	}
+
+	imgWidth, imgHeight := lbp.GetImageSize(img)
+	if imgWidth <= 0 || imgHeight <= 0 {
+		return errors.New("The image has an invalid size")
 	}
-	// Check if the first image is nil
-	if images[0] == nil {
-		return errors.New("At least one image in the slice is nil")
+	if imgWidth != width || imgHeight != height {
+		return fmt.Errorf("The image size (%dx%d) is different from the size of the other images (%dx%d)",
+			imgWidth, imgHeight, width, height)
 	}
+	return nil
+}
 
-	// Get the image size from the first image
-	defaultWidth, defaultHeight := lbp.GetImageSize(images[0])
+// maskWeights struct stores the weights of the Mask aligned with the pixels
+// returned by the LBP operation and the size of the Mask.
+type maskWeights struct {
+	weights [][]float64
+	width   int
+	height  int
+}
 
-	// Check if the size is valid
-	// This condition should never happen because
-	// we already tested if the image was nil
-	if defaultWidth <= 0 || defaultHeight <= 0 {
-		return errors.New("At least one image have an invalid size")
+// getMaskWeights function converts the Mask to a 'matrix' of weights. It returns
+// nil if no Mask is defined. The conversion is done once per call of the Train,
+// Update and Predict functions and the weights are shared by all images.
+func getMaskWeights() *maskWeights {
+	if Mask == nil {
+		return nil
 	}
 
-	// Check each image in the slice
-	for index := 0; index < len(images); index++ {
-		// Check if the current image is nil
-		if images[index] == nil {
-			return errors.New("At least one image in the slice is nil")
+	// The LBP operation does not calculate the border pixels
+	width, height := lbp.GetImageSize(Mask)
+	return &maskWeights{weights: mask.Trim(mask.FromImage(Mask), 1), width: width, height: height}
+}
+
+// extractHistogram function calculates the LBP operation for the image
+// passed by parameter and returns its histogram (feature vector). The
+// weights of the Mask (see getMaskWeights) are nil if no Mask is defined.
+func extractHistogram(img image.Image, weights *maskWeights) ([]float64, error) {
+	// Check if the mask has the same size as the image
+	var pixelWeights [][]float64
+	if weights != nil {
+		width, height := lbp.GetImageSize(img)
+		if weights.width != width || weights.height != height {
+			return nil, errors.New("The mask and the image have different sizes")
 		}
+		pixelWeights = weights.weights
+	}
 
-		// Get the size from the current image
-		width, height := lbp.GetImageSize(images[index])
+	// Calculate the LBP operation.
+	pixels, err := lbp.Calculate(img, lbphParams.Radius, lbphParams.Neighbors)
+	if err != nil {
+		return nil, err
+	}
 
-		// Check if all images have the same size
-		if width != defaultWidth || height != defaultHeight {
-			return errors.New("One or more images have different sizes")
-		}
+	// Calculate the histogram for the image.
+	hist, err := histogram.CalculateWithLayout(pixels, pixelWeights, getLayout())
+	if err != nil || !lbphParams.Normalize {
+		return hist, err
 	}
-	// No error has occurred, return nil
-	return nil
+	return histogram.Normalize(hist), nil
+}
+
+// getLayout function returns the histogram layout based on the LBPH parameters.
+// The custom regions take precedence over the uniform grid.
+func getLayout() histogram.Layout {
+	if len(lbphParams.Regions) > 0 {
+		return histogram.NewRegionLayout(lbphParams.Regions)
+	}
+	return histogram.NewGridLayout(lbphParams.GridX, lbphParams.GridY)
+}
+
+// fitMetric function estimates the parameters of the selected metric from the
+// training histograms. It returns nil if the metric does not need to be trained.
+func fitMetric(selectedMetric metric.Metric, histograms [][]float64) (metric.Metric, error) {
+	fitter, ok := metric.Resolve(selectedMetric).(metric.Fitter)
+	if !ok {
+		return nil, nil
+	}
+	return fitter.Fit(histograms)
+}
+
+// getMetric function returns the metric used to compare the histograms.
+// If the selected Metric was trained, the fitted metric is returned.
+func getMetric() metric.Metric {
+	if trainingData.Metric != nil && Metric != nil && trainingData.Metric.Name() == Metric.Name() {
+		return trainingData.Metric
+	}
+	return Metric
 }
 
 // Train function is used for training the LBPH algorithm based on the
 // images and labels passed by parameter. It basically checks the input
 // data, calculates the LBP operation and gets the histogram of each image.
+// The histograms are calculated in parallel (see the Workers variable) and
+// if some images fail (e.g. nil images or images with a different size from the
+// first image), an ExtractionError with their indexes is returned.
 func Train(images []image.Image, labels []string) error {
+	return train(images, labels, RetainImages)
+}
+
+// train function trains the algorithm (see the Train function). The images are
+// only stored if retain is true.
+func train(images []image.Image, labels []string, retain bool) error {
 	// Clear the data structure
 	trainingData = nil
 
@@ -159,84 +302,155 @@ func Train(images []image.Image, labels []string) error {
 		return errors.New("The slices have different sizes")
 	}
 
-	// Call the CheckImagesSizes from the common package.
-	// It will check if all images have the same size.
-	err := checkImagesSizes(images)
+	// Calculates the LBP operation and gets the histograms for each image (in parallel).
+	// The nil images and the images with a different size are reported by index.
+	width, height := getImagesSize(images)
+	histograms, err := extractHistograms(images, width, height)
 	if err != nil {
 		return err
 	}
 
-	// Calculates the LBP operation and gets the histograms for each image.
-	var histograms [][]float64
-	for index := 0; index < len(images); index++ {
-		// Calculate the LBP operation for the current image.
-		pixels, err := lbp.Calculate(images[index], lbphParams.Radius, lbphParams.Neighbors)
-		if err != nil {
-			return err
-		}
+	// Store the current data that we are working on.
+	// The images are only stored if they are retained.
+	if !retain {
+		images = nil
+	}
+	trainingData, err = newTrainingData(newSettings(), images, labels, histograms, width, height)
+	if err != nil {
+		return err
+	}
 
-		// Get the histogram from the current image.
-		hist, err := histogram.Calculate(pixels, lbphParams.GridX, lbphParams.GridY)
-		if err != nil {
-			return err
-		}
+	// Everything is ok, return nil.
+	return nil
+}
+
+// newSettings function returns the settings (LBPH parameters, selected metric,
+// prototype matching mode and mask) based on the current state. It is used by the
+// Train function.
+func newSettings() *TrainingData {
+	return &TrainingData{Params: lbphParams, SelectedMetric: Metric, Prototypes: Prototypes, Mask: Mask}
+}
 
-		// Store the histogram in the 'matrix' (slice of slice).
-		histograms = append(histograms, hist)
+// newTrainingData function creates the training data based on the images, labels and
+// histograms passed by parameter. It also estimates the parameters of the metric and
+// calculates the prototypes (if needed), so it is used by the Train and Update functions.
+// The width and height are the size of the images. The settings (LBPH parameters,
+// selected metric, prototype matching mode and mask) are copied from the settings parameter,
+// so the functions that change the training data keep the settings used to train it.
+func newTrainingData(settings *TrainingData, images []image.Image, labels []string, histograms [][]float64, width, height int) (*TrainingData, error) {
+	// Estimate the parameters of the metric (if needed).
+	fittedMetric, err := fitMetric(settings.SelectedMetric, histograms)
+	if err != nil {
+		return nil, err
 	}
 
-	// Store the current data that we are working on.
-	trainingData = &TrainingData{
-		Images:     images,
-		Labels:     labels,
-		Histograms: histograms,
+	// Collapse the histograms of each label into prototypes (if enabled).
+	selectedMetric := settings.SelectedMetric
+	if fittedMetric != nil {
+		selectedMetric = fittedMetric
+	}
+	prototypes, prototypeLabels, err := calculatePrototypes(settings.Prototypes, histograms, labels, selectedMetric)
+	if err != nil {
+		return nil, err
 	}
 
-	// Everything is ok, return nil.
-	return nil
+	data := &TrainingData{
+		Images:              images,
+		Labels:              labels,
+		Histograms:          histograms,
+		Params:              settings.Params,
+		Width:               width,
+		Height:              height,
+		SelectedMetric:      settings.SelectedMetric,
+		Metric:              fittedMetric,
+		PrototypeHistograms: prototypes,
+		PrototypeLabels:     prototypeLabels,
+		Prototypes:          settings.Prototypes,
+		Mask:                settings.Mask,
+	}
+
+	// Convert the histograms to the compact storage (if selected).
+	if err := data.compactHistograms(); err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
-// Predict function is used to find the closest image based on the images used in the training step.
-func Predict(img image.Image) (string, float64, error) {
+// Prediction struct stores the comparison between an image and one of the
+// training images: its label, the distance between the histograms and the
+// index of the training image (in the TrainingData slices). If the prototype
+// matching is enabled, the index refers to the PrototypeHistograms slice.
+// If the algorithm was trained using sample records, the Sample is also returned.
+type Prediction struct {
+	Label    string
+	Distance float64
+	Index    int
+	Sample   Sample
+}
+
+// getPredictHistogram function checks the training data and the image passed
+// by parameter and calculates its histogram. It is used by the predict functions.
+func getPredictHistogram(img image.Image, weights *maskWeights) ([]float64, error) {
 
 	// Check if we have data in the trainingData struct.
 	if trainingData == nil {
-		return "", 0.0, errors.New("The algorithm was not trained yet")
+		return nil, errors.New("The algorithm was not trained yet")
 	}
 
 	// Check if the image passed by parameter is nil.
 	if img == nil {
-		return "", 0.0, errors.New("The image passed by parameter is nil")
+		return nil, errors.New("The image passed by parameter is nil")
 	}
 
 	// If we don't have histograms to compare, probably the Train function was
 	// not called or has occurred an error and it was not correctly treated.
-	if len(trainingData.Histograms) == 0 {
-		return "", 0.0, errors.New("There are no histograms in the trainData")
+	if len(trainingData.Labels) == 0 {
+		return nil, errors.New("There are no histograms in the trainData")
 	}
 
-	// Calculate the LBP operation.
-	pixels, err := lbp.Calculate(img, lbphParams.Radius, lbphParams.Neighbors)
-	if err != nil {
-		return "", 0.0, err
+	// Calculate the histogram for the image.
+	return extractHistogram(img, weights)
+}
+
+// Predict function is used to find the closest image based on the images used in the training step.
+// If the distance is higher than the threshold, it returns an empty label and the ErrUnknown error.
+// If the k-NN decision mode is selected (Decision.K higher than 1), it returns the winning label and
+// the distance to the closest training image with this label.
+func Predict(img image.Image) (string, float64, error) {
+	return predict(img, getMaskWeights())
+}
+
+// predict function predicts the image (see the Predict function) using the
+// weights of the Mask passed by parameter.
+func predict(img image.Image, weights *maskWeights) (string, float64, error) {
+
+	// Use the k-NN decision mode.
+	if Decision.K > 1 {
+		result, err := predictKNN(img, weights)
+		if err != nil {
+			return result.Label, result.Distance, err
+		}
+		return result.Label, result.Distance, nil
 	}
 
-	// Calculate the histogram for the image.
-	hist, err := histogram.Calculate(pixels, lbphParams.GridX, lbphParams.GridY)
+	// Check the training data and calculate the histogram for the image.
+	hist, err := getPredictHistogram(img, weights)
 	if err != nil {
 		return "", 0.0, err
 	}
 
 	// Search for the closest histogram based on the histograms calculated in the training step.
-	minDistance, err := histogram.Compare(hist, trainingData.Histograms[0], Metric)
+	selectedMetric := getMetric()
+	gallery := getGallery()
+	minDistance, err := gallery.compare(hist, 0, selectedMetric)
 	if err != nil {
 		return "", 0.0, err
 	}
 
 	minIndex := 0
-	for index := 1; index < len(trainingData.Histograms); index++ {
+	for index := 1; index < gallery.size(); index++ {
 		// Calculate the distance from the current histogram.
-		distance, err := histogram.Compare(hist, trainingData.Histograms[index], Metric)
+		distance, err := gallery.compare(hist, index, selectedMetric)
 		if err != nil {
 			return "", 0.0, err
 		}
@@ -248,7 +462,66 @@ func Predict(img image.Image) (string, float64, error) {
 		}
 	}
 
+	// Check if the image is unknown (distance higher than the threshold).
+	if err := checkThreshold(gallery.labels[minIndex], minDistance); err != nil {
+		return "", minDistance, err
+	}
+
 	// Return the label corresponding to the closest histogram,
 	// the distance (minDistance) and the error (nil).
-	return trainingData.Labels[minIndex], minDistance, nil
+	return gallery.labels[minIndex], minDistance, nil
+}
+
+// PredictTopK function is used to find the K closest images based on the images
+// used in the training step. The predictions are sorted by distance (ascending)
+// and the ties are sorted by the training index. If K is higher than the number
+// of training images, all images are returned.
+func PredictTopK(img image.Image, k int) ([]Prediction, error) {
+	return predictTopK(img, k, getMaskWeights())
+}
+
+// predictTopK function finds the K closest images (see the PredictTopK function)
+// using the weights of the Mask passed by parameter.
+func predictTopK(img image.Image, k int, weights *maskWeights) ([]Prediction, error) {
+
+	// Check the K parameter.
+	if k <= 0 {
+		return nil, errors.New("The K parameter should be higher than 0")
+	}
+
+	// Check the training data and calculate the histogram for the image.
+	hist, err := getPredictHistogram(img, weights)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare the histogram to all histograms calculated in the training step.
+	selectedMetric := getMetric()
+	gallery := getGallery()
+	predictions := make([]Prediction, gallery.size())
+	for index := 0; index < gallery.size(); index++ {
+		distance, err := gallery.compare(hist, index, selectedMetric)
+		if err != nil {
+			return nil, err
+		}
+
+		predictions[index] = Prediction{
+			Label:    gallery.labels[index],
+			Distance: distance,
+			Index:    index,
+		}
+		if gallery.samples != nil {
+			predictions[index].Sample = gallery.samples[index]
+		}
+	}
+
+	// Sort the predictions by distance keeping the training order for ties.
+	sort.SliceStable(predictions, func(i, j int) bool {
+		return predictions[i].Distance < predictions[j].Distance
+	})
+
+	if k < len(predictions) {
+		predictions = predictions[:k]
+	}
+	return predictions, nil
 }
@@ -1,10 +1,15 @@
 package lbph
 
 import (
+	"bytes"
 	"image"
+	"math"
 	"os"
+	"runtime"
 	"testing"
 
+	"github.com/kelvins/lbph/histogram"
+	"github.com/kelvins/lbph/mask"
 	"github.com/kelvins/lbph/metric"
 
 	"github.com/stretchr/testify/assert"
@@ -95,3 +100,315 @@ func TestPredict(t *testing.T) {
 		assert.Equal(t, trainData.Labels[index], labels[index], "The labels should be equal")
 	}
 }
+
+func TestMask(t *testing.T) {
+	Init(Params{})
+	defer func() { Mask = nil }()
+
+	img, err := LoadImage("./dataset/train/1.png")
+	assert.Nil(t, err)
+
+	width, height := img.Bounds().Max.X, img.Bounds().Max.Y
+
+	// A mask with a different size should return an error
+	Mask = image.NewGray(image.Rect(0, 0, width+1, height))
+	err = Train([]image.Image{img}, []string{"rocks"})
+	assert.NotNil(t, err)
+
+	// Pixels outside the ellipse should not be counted
+	Mask = mask.Ellipse(width, height)
+	err = Train([]image.Image{img}, []string{"rocks"})
+	assert.Nil(t, err)
+
+	var total float64
+	for _, value := range GetTrainingData().Histograms[0] {
+		total += value
+	}
+	assert.True(t, total > 0)
+	assert.True(t, total < float64((width-2)*(height-2)))
+
+	label, _, err := Predict(img)
+	assert.Nil(t, err)
+	assert.Equal(t, "rocks", label)
+}
+
+func TestRegions(t *testing.T) {
+	Init(Params{
+		Regions: []histogram.Region{
+			{X: 0, Y: 0, Width: 1, Height: 0.5},
+			{X: 0.25, Y: 0.5, Width: 0.5, Height: 0.5},
+		},
+	})
+	defer Init(Params{})
+
+	img, err := LoadImage("./dataset/train/1.png")
+	assert.Nil(t, err)
+
+	err = Train([]image.Image{img}, []string{"rocks"})
+	assert.Nil(t, err)
+	assert.Equal(t, 512, len(GetTrainingData().Histograms[0]))
+}
+
+// trainDataset function trains the algorithm using the images from the dataset.
+func trainDataset(t *testing.T) {
+	Init(Params{})
+
+	paths := []string{"./dataset/train/1.png", "./dataset/train/2.png", "./dataset/train/3.png"}
+	labels := []string{"rocks", "grass", "wood"}
+
+	var images []image.Image
+	for index := 0; index < len(paths); index++ {
+		img, err := LoadImage(paths[index])
+		assert.Nil(t, err)
+		images = append(images, img)
+	}
+
+	err := Train(images, labels)
+	assert.Nil(t, err)
+}
+
+func TestPredictMetrics(t *testing.T) {
+	trainDataset(t)
+	defer func() { Metric = metric.EuclideanDistance }()
+
+	// Table tests
+	var tTable = []struct {
+		path  string
+		label string
+	}{
+		{"./dataset/test/1.png", "wood"},
+		{"./dataset/test/2.png", "rocks"},
+		{"./dataset/test/3.png", "grass"},
+	}
+
+	var metrics = []metric.Metric{
+		metric.ChiSquare,
+		metric.Intersection,
+		metric.Correlation,
+		metric.BhattacharyyaDistance,
+		metric.HellingerDistance,
+		metric.KullbackLeiblerDivergence,
+		metric.JensenShannonDivergence,
+		metric.LogLikelihood,
+		metric.QuadraticFormDistance,
+	}
+
+	for _, selectedMetric := range metrics {
+		Metric = selectedMetric
+		for _, pair := range tTable {
+			img, err := LoadImage(pair.path)
+			assert.Nil(t, err)
+			label, distance, err := Predict(img)
+			assert.Nil(t, err)
+			assert.Equal(t, pair.label, label, "The labels should be equal")
+			assert.False(t, math.IsNaN(distance) || math.IsInf(distance, 0))
+		}
+	}
+}
+
+func TestPredictEarthMoversDistance(t *testing.T) {
+	trainDataset(t)
+	defer func() { Metric = metric.EuclideanDistance }()
+
+	img, err := LoadImage("./dataset/test/1.png")
+	assert.Nil(t, err)
+
+	// The LBP codes order is not meaningful for these textures,
+	// so only check that the metrics can be selected
+	for _, selectedMetric := range []metric.Metric{metric.EarthMoversDistance, metric.CyclicEarthMoversDistance} {
+		Metric = selectedMetric
+		_, distance, err := Predict(img)
+		assert.Nil(t, err)
+		assert.True(t, distance > 0)
+	}
+}
+
+func TestMahalanobisDistance(t *testing.T) {
+	defer func() { Metric = metric.EuclideanDistance }()
+
+	// The metric is not trained
+	trainDataset(t)
+	Metric = metric.MahalanobisDistance
+	img, err := LoadImage("./dataset/test/1.png")
+	assert.Nil(t, err)
+	_, _, err = Predict(img)
+	assert.NotNil(t, err)
+
+	// The covariance is estimated in the training step
+	trainDataset(t)
+	assert.NotNil(t, GetTrainingData().Metric)
+	assert.Equal(t, string(metric.MahalanobisDistance), GetTrainingData().Metric.Name())
+
+	label, _, err := Predict(img)
+	assert.Nil(t, err)
+	assert.Equal(t, "wood", label)
+
+	// Metrics that do not need training are not stored
+	Metric = metric.CosineDistance
+	trainDataset(t)
+	assert.Nil(t, GetTrainingData().Metric)
+
+	label, _, err = Predict(img)
+	assert.Nil(t, err)
+	assert.Equal(t, "wood", label)
+}
+
+func TestPredictTopK(t *testing.T) {
+	trainDataset(t)
+
+	img, err := LoadImage("./dataset/test/1.png")
+	assert.Nil(t, err)
+
+	_, err = PredictTopK(img, 0)
+	assert.NotNil(t, err)
+
+	_, err = PredictTopK(nil, 1)
+	assert.NotNil(t, err)
+
+	predictions, err := PredictTopK(img, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(predictions))
+
+	// The first prediction should be the same as the Predict function
+	label, distance, err := Predict(img)
+	assert.Nil(t, err)
+	assert.Equal(t, label, predictions[0].Label)
+	assert.Equal(t, distance, predictions[0].Distance)
+	assert.Equal(t, 2, predictions[0].Index)
+	assert.True(t, predictions[0].Distance <= predictions[1].Distance)
+
+	// K higher than the number of training images
+	predictions, err = PredictTopK(img, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(predictions))
+	for index := 1; index < len(predictions); index++ {
+		assert.True(t, predictions[index-1].Distance <= predictions[index].Distance)
+		assert.Equal(t, GetTrainingData().Labels[predictions[index].Index], predictions[index].Label)
+	}
+}
+
+func TestRetainImages(t *testing.T) {
+	defer func() {
+		RetainImages = true
+		RetainUpdateImages = false
+	}()
+
+	for _, retain := range []bool{true, false} {
+		RetainImages = retain
+		RetainUpdateImages = retain
+		images := trainSamples(t)
+		trainData := GetTrainingData()
+		assert.Equal(t, 5, len(trainData.Labels))
+		assert.Equal(t, 5, len(trainData.Histograms))
+
+		err := Update(images[:1], []string{"rocks"})
+		assert.Nil(t, err)
+
+		trainData = GetTrainingData()
+		assert.Equal(t, 6, len(trainData.Histograms))
+		if retain {
+			assert.Equal(t, 6, len(trainData.Images))
+		} else {
+			assert.Nil(t, trainData.Images)
+		}
+
+		label, _, err := Predict(images[0])
+		assert.Nil(t, err)
+		assert.Equal(t, "rocks", label)
+	}
+}
+
+func TestRetainUpdateImages(t *testing.T) {
+	images := trainSamples(t)
+	assert.Equal(t, 5, len(GetTrainingData().Images))
+
+	// The images are dropped by default (even the images retained by Train)
+	err := Update(images[:1], []string{"rocks"})
+	assert.Nil(t, err)
+	assert.Nil(t, GetTrainingData().Images)
+
+	err = TrainSamples(images[:2], []Sample{{ID: 1, Label: "rocks"}, {ID: 2, Label: "grass"}})
+	assert.Nil(t, err)
+	assert.Nil(t, GetTrainingData().Images)
+
+	err = UpdateSamples(images[2:3], []Sample{{ID: 3, Label: "wood"}})
+	assert.Nil(t, err)
+	assert.Nil(t, GetTrainingData().Images)
+
+	Init(Params{})
+	err = Update(images[:2], []string{"rocks", "grass"})
+	assert.Nil(t, err)
+	assert.Nil(t, GetTrainingData().Images)
+
+	// Both models have the images (they are saved), but they are dropped by Merge
+	trainSamples(t)
+	var buf bytes.Buffer
+	SaveImages = true
+	err = Save(&buf)
+	SaveImages = false
+	assert.Nil(t, err)
+
+	trainSamples(t)
+	err = Merge(bytes.NewReader(buf.Bytes()), MergeKeepBoth)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(GetTrainingData().Labels))
+	assert.Nil(t, GetTrainingData().Images)
+
+	// Opt in
+	RetainUpdateImages = true
+	defer func() { RetainUpdateImages = false }()
+	trainSamples(t)
+	err = Merge(bytes.NewReader(buf.Bytes()), MergeKeepBoth)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(GetTrainingData().Images))
+}
+
+// benchmarkRetainImages function trains the algorithm with the dataset images (loaded
+// in every iteration, like a real pipeline) and logs the heap retained by the model.
+func benchmarkRetainImages(b *testing.B, retain bool) {
+	defer func() {
+		RetainImages = true
+		Init(Params{})
+	}()
+	RetainImages = retain
+	Init(Params{})
+
+	paths := []string{"./dataset/train/1.png", "./dataset/train/2.png", "./dataset/train/3.png"}
+	var labels []string
+	for index := 0; index < 30; index++ {
+		labels = append(labels, paths[index%len(paths)])
+	}
+
+	var before, after runtime.MemStats
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		trainingData = nil
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		var images []image.Image
+		for _, path := range labels {
+			img, err := LoadImage(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			images = append(images, img)
+		}
+		if err := Train(images, labels); err != nil {
+			b.Fatal(err)
+		}
+		images = nil
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+	}
+	b.Logf("retained: %d B", int64(after.HeapAlloc)-int64(before.HeapAlloc))
+}
+
+func BenchmarkTrainRetainImages(b *testing.B) {
+	benchmarkRetainImages(b, true)
+}
+
+func BenchmarkTrainWithoutImages(b *testing.B) {
+	benchmarkRetainImages(b, false)
+}
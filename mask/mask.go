@@ -0,0 +1,84 @@
+// mask package provides helpers to build the masks used to ignore some regions
+// of the images (e.g. background, hair or mouth) when calculating the histograms.
+package mask
+
+import (
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// FromImage function converts the image passed by parameter to a 'matrix' of
+// weights ([][]float64) in the [0, 1] interval. Each pixel is converted to
+// grayscale, so black pixels (0) are masked out and white pixels (255) are fully
+// counted. Binary masks should only use black and white pixels.
+// The 'matrix' uses the same [x][y] layout as the lbp.GetPixels function.
+func FromImage(img image.Image) [][]float64 {
+	var weights [][]float64
+
+	// Check if the image is nil
+	if img == nil {
+		return weights
+	}
+
+	// Get the image size
+	bounds := img.Bounds()
+	width, height := bounds.Max.X, bounds.Max.Y
+
+	for x := 0; x < width; x++ {
+		var row []float64
+		for y := 0; y < height; y++ {
+			// Convert the current pixel to grayscale (0-255)
+			gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+
+			// Normalize the weight to the [0, 1] interval
+			row = append(row, float64(gray.Y)/255.0)
+		}
+		weights = append(weights, row)
+	}
+
+	return weights
+}
+
+// Trim function removes the border (number of pixels) from each side of the
+// weights 'matrix'. It is used to align the mask with the pixels returned by the
+// lbp.Calculate function, which does not calculate the LBP for the image border.
+func Trim(weights [][]float64, border int) [][]float64 {
+	var trimmed [][]float64
+
+	for x := border; x < len(weights)-border; x++ {
+		if len(weights[x]) <= 2*border {
+			continue
+		}
+		trimmed = append(trimmed, weights[x][border:len(weights[x])-border])
+	}
+
+	return trimmed
+}
+
+// Ellipse function creates a binary elliptical mask (e.g. to select the face
+// region and ignore the background and hair) with the size passed by parameter.
+// The ellipse is centered and its axes are equal to the image width and height.
+// Pixels inside the ellipse are white (255) and pixels outside are black (0).
+func Ellipse(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+
+	// Center and semi-axes of the ellipse
+	centerX := float64(width) / 2.0
+	centerY := float64(height) / 2.0
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			// Use the center of the pixel to check if it is inside the ellipse
+			dx := (float64(x) + 0.5 - centerX) / centerX
+			dy := (float64(y) + 0.5 - centerY) / centerY
+			if dx*dx+dy*dy <= 1.0 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,64 @@
+package mask
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromImage(t *testing.T) {
+	weights := FromImage(nil)
+	assert.Equal(t, 0, len(weights))
+
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	img.SetGray(0, 0, color.Gray{Y: 255})
+	img.SetGray(1, 1, color.Gray{Y: 51})
+
+	expectedWeights := [][]float64{{1.0, 0.0}, {0.0, 0.2}, {0.0, 0.0}}
+
+	weights = FromImage(img)
+	assert.Equal(t, expectedWeights, weights, "The weights should be equal")
+}
+
+func TestTrim(t *testing.T) {
+	weights := [][]float64{
+		{1, 1, 1, 1},
+		{1, 2, 3, 1},
+		{1, 4, 5, 1},
+		{1, 1, 1, 1},
+	}
+
+	expectedWeights := [][]float64{{2, 3}, {4, 5}}
+	assert.Equal(t, expectedWeights, Trim(weights, 1), "The weights should be equal")
+
+	assert.Equal(t, 0, len(Trim(weights, 2)))
+}
+
+func TestEllipse(t *testing.T) {
+	img := Ellipse(10, 6)
+
+	width, height := img.Bounds().Max.X, img.Bounds().Max.Y
+	assert.Equal(t, 10, width)
+	assert.Equal(t, 6, height)
+
+	// Table tests
+	var tTable = []struct {
+		x     int
+		y     int
+		value uint8
+	}{
+		{5, 3, 255},
+		{0, 3, 255},
+		{9, 2, 255},
+		{0, 0, 0},
+		{9, 5, 0},
+		{0, 5, 0},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		assert.Equal(t, pair.value, img.GrayAt(pair.x, pair.y).Y, "The mask value should be equal")
+	}
+}
@@ -0,0 +1,148 @@
+package math
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// checkRegions check if the histograms can be split in regions of the same size.
+func checkRegions(hist1, hist2 []float64, bins int) error {
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return err
+	}
+	if bins <= 0 || len(hist1)%bins != 0 {
+		return errors.New("Could not compare the histograms. Invalid number of bins per region.")
+	}
+	return nil
+}
+
+// cdfDifferences calculates the difference between the cumulative distributions
+// of the two region histograms, each one normalized to sum 1.
+// Empty regions are kept as zeros.
+func cdfDifferences(region1, region2 []float64) []float64 {
+	total1 := sum(region1)
+	total2 := sum(region2)
+
+	differences := make([]float64, len(region1))
+	var cdf1, cdf2 float64
+	for index := 0; index < len(region1); index++ {
+		if total1 > 0 {
+			cdf1 += region1[index] / total1
+		}
+		if total2 > 0 {
+			cdf2 += region2[index] / total2
+		}
+		differences[index] = cdf1 - cdf2
+	}
+	return differences
+}
+
+// EarthMoversDistance calculates the 1D Earth Mover's Distance between two
+// histograms composed of regions with the same number of bins (e.g. 256 for
+// the LBP histograms). Each region is normalized to sum 1 and the distances
+// of all regions are summed. It is a cross-bin metric, so small code shifts
+// caused by noise have a small cost.
+// EMD = \sum_{i=1}^{n} \left | CDF1_{i} - CDF2_{i} \right |
+// Reference: https://en.wikipedia.org/wiki/Earth_mover%27s_distance
+func EarthMoversDistance(hist1, hist2 []float64, bins int) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkRegions(hist1, hist2, bins); err != nil {
+		return 0.0, err
+	}
+
+	var distance float64
+	for start := 0; start < len(hist1); start += bins {
+		differences := cdfDifferences(hist1[start:start+bins], hist2[start:start+bins])
+		for _, difference := range differences {
+			distance += math.Abs(difference)
+		}
+	}
+	return distance, nil
+}
+
+// CyclicEarthMoversDistance calculates the 1D Earth Mover's Distance between
+// two histograms considering that the bins are cyclic (the last bin is next to
+// the first one). It works by regions like the EarthMoversDistance function.
+// CEMD = \min_{c} \sum_{i=1}^{n} \left | CDF1_{i} - CDF2_{i} - c \right |
+// where the minimum is reached when c is the median of the differences.
+// Reference:
+// Rabin, Julien, Julie Delon, and Yann Gousseau. "Circular Earth Mover's Distance for the comparison of local features."
+func CyclicEarthMoversDistance(hist1, hist2 []float64, bins int) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkRegions(hist1, hist2, bins); err != nil {
+		return 0.0, err
+	}
+
+	var distance float64
+	for start := 0; start < len(hist1); start += bins {
+		differences := cdfDifferences(hist1[start:start+bins], hist2[start:start+bins])
+
+		// Get the median of the differences
+		sorted := make([]float64, len(differences))
+		copy(sorted, differences)
+		sort.Float64s(sorted)
+		median := sorted[len(sorted)/2]
+
+		for _, difference := range differences {
+			distance += math.Abs(difference - median)
+		}
+	}
+	return distance, nil
+}
+
+// QuadraticFormDistance calculates the quadratic-form distance between two
+// histograms composed of regions with the same number of bins (a power of 2,
+// e.g. 256 for the LBP histograms). The similarity between two LBP codes i and j
+// is defined as similarity^hamming(i, j), so codes that differ by a few bits
+// (e.g. due to noise) are considered similar. With similarity equal to 0 it is
+// the same as the EuclideanDistance.
+// D = \sqrt{(hist1 - hist2)^T A (hist1 - hist2)}, where A_{ij} = s^{hamming(i, j)}
+// As the similarity matrix is the Kronecker product of 2x2 matrices, it is
+// always positive semi-definite and it is applied without building the matrix.
+// Reference:
+// Hafner, James, et al. "Efficient color histogram indexing for quadratic form distance functions."
+func QuadraticFormDistance(hist1, hist2 []float64, bins int, similarity float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkRegions(hist1, hist2, bins); err != nil {
+		return 0.0, err
+	}
+	if bins&(bins-1) != 0 {
+		return 0.0, errors.New("Could not compare the histograms. The number of bins per region is not a power of 2.")
+	}
+	if similarity < 0 || similarity > 1 {
+		return 0.0, errors.New("Could not compare the histograms. The similarity should be between 0 and 1.")
+	}
+
+	var sum float64
+	difference := make([]float64, bins)
+	for start := 0; start < len(hist1); start += bins {
+		for index := 0; index < bins; index++ {
+			difference[index] = hist1[start+index] - hist2[start+index]
+		}
+
+		// Apply the similarity matrix (A * difference) one bit at a time
+		transformed := make([]float64, bins)
+		copy(transformed, difference)
+		for bit := 1; bit < bins; bit <<= 1 {
+			for index := 0; index < bins; index++ {
+				if index&bit == 0 {
+					value1 := transformed[index]
+					value2 := transformed[index|bit]
+					transformed[index] = value1 + similarity*value2
+					transformed[index|bit] = similarity*value1 + value2
+				}
+			}
+		}
+
+		for index := 0; index < bins; index++ {
+			sum += difference[index] * transformed[index]
+		}
+	}
+
+	// The sum should never be negative, but it can be due to rounding errors
+	return math.Sqrt(math.Max(sum, 0)), nil
+}
@@ -0,0 +1,101 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEarthMoversDistance(t *testing.T) {
+	// Table tests
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		bins     int
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{8, 7, 6, 5, 4, 3, 2, 1}, 8, 2.333333333333333},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{8, 7, 6, 5, 4, 3, 2, 1}, 4, 1.3846153846153846},
+		{[]float64{1, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 1, 0, 0, 0, 0, 0, 0}, 8, 1.0},
+		{[]float64{1, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 0, 0, 1}, 8, 7.0},
+		{[]float64{4, 0, 0, 0, 0, 0, 0, 0}, []float64{4, 0, 0, 0, 0, 0, 0, 0}, 8, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0}, []float64{0, 0, 0, 1, 3, 4, 2, 0}, 8, 2.7},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0}, []float64{0, 0, 0, 1, 3, 4, 2, 0}, 4, 2.031746031746032},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := EarthMoversDistance(pair.hist1, pair.hist2, pair.bins)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-12)
+	}
+
+	_, err := EarthMoversDistance([]float64{1, 2, 3}, []float64{1, 2, 3}, 2)
+	assert.NotNil(t, err)
+
+	_, err = EarthMoversDistance([]float64{1, 2}, []float64{1, 2}, 0)
+	assert.NotNil(t, err)
+}
+
+func TestCyclicEarthMoversDistance(t *testing.T) {
+	// Table tests
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		bins     int
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{8, 7, 6, 5, 4, 3, 2, 1}, 8, 0.8888888888888887},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{8, 7, 6, 5, 4, 3, 2, 1}, 4, 0.553846153846154},
+		{[]float64{1, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 1, 0, 0, 0, 0, 0, 0}, 8, 1.0},
+		// The first and last bins are neighbors
+		{[]float64{1, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 0, 0, 1}, 8, 1.0},
+		{[]float64{4, 0, 0, 0, 0, 0, 0, 0}, []float64{4, 0, 0, 0, 0, 0, 0, 0}, 8, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0}, []float64{0, 0, 0, 1, 3, 4, 2, 0}, 8, 2.1666666666666665},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0}, []float64{0, 0, 0, 1, 3, 4, 2, 0}, 4, 1.8888888888888888},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := CyclicEarthMoversDistance(pair.hist1, pair.hist2, pair.bins)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-12)
+	}
+
+	_, err := CyclicEarthMoversDistance([]float64{1, 2, 3}, []float64{1, 2}, 1)
+	assert.NotNil(t, err)
+}
+
+func TestQuadraticFormDistance(t *testing.T) {
+	// Table tests (reference values calculated by building the similarity matrix)
+	var tTable = []struct {
+		hist1      []float64
+		hist2      []float64
+		bins       int
+		similarity float64
+		distance   float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{8, 7, 6, 5, 4, 3, 2, 1}, 8, 0.5, 13.74772708486752},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{8, 7, 6, 5, 4, 3, 2, 1}, 4, 0.5, 17.832554500127006},
+		{[]float64{1, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 1, 0, 0, 0, 0, 0, 0}, 8, 0.5, 1.0},
+		{[]float64{1, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 0, 0, 1}, 8, 0.5, 1.3228756555322954},
+		{[]float64{4, 0, 0, 0, 0, 0, 0, 0}, []float64{4, 0, 0, 0, 0, 0, 0, 0}, 8, 0.5, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0}, []float64{0, 0, 0, 1, 3, 4, 2, 0}, 8, 0.5, 9.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0}, []float64{0, 0, 0, 1, 3, 4, 2, 0}, 4, 0.5, 11.76860229593982},
+		// Without similarity between the codes it is the euclidean distance
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0}, []float64{0, 0, 0, 1, 3, 4, 2, 0}, 8, 0.0, 8.660254037844387},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := QuadraticFormDistance(pair.hist1, pair.hist2, pair.bins, pair.similarity)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-12)
+	}
+
+	_, err := QuadraticFormDistance([]float64{1, 2, 3, 4, 5, 6}, []float64{1, 2, 3, 4, 5, 6}, 3, 0.5)
+	assert.NotNil(t, err)
+
+	_, err = QuadraticFormDistance([]float64{1, 2}, []float64{1, 2}, 2, 1.5)
+	assert.NotNil(t, err)
+}
@@ -5,6 +5,9 @@ import (
 	"math"
 )
 
+// epsilon is the machine epsilon for float64 (same as DBL_EPSILON in C).
+const epsilon = 2.220446049250313e-16
+
 // checkHistograms check if the histograms are correct.
 func checkHistograms(hist1, hist2 []float64) error {
 	if len(hist1) == 0 || len(hist2) == 0 {
@@ -16,8 +19,11 @@ func checkHistograms(hist1, hist2 []float64) error {
 	return nil
 }
 
-// chiSquare calculates the distance between two histograms using
-// the chi square statistic.
+// ChiSquare calculates the distance between two histograms using
+// the (asymmetric) chi square statistic. It returns +Inf if a bin of
+// hist1 is empty and the same bin of hist2 is not, so SymmetricChiSquare
+// is recommended for LBP histograms. Bins that are empty in both histograms
+// do not contribute to the distance.
 // x^2 = \sum_{i=1}^{n}\frac{(hist1_{i} - hist2_{i})^2}{hist1_{i}}
 // References:
 // http://file.scirp.org/Html/8-72278_30995.htm
@@ -31,6 +37,9 @@ func ChiSquare(hist1, hist2 []float64) (float64, error) {
 
 	var sum float64
 	for index := 0; index < len(hist1); index++ {
+		if hist1[index] == 0 && hist2[index] == 0 {
+			continue
+		}
 		numerator := math.Pow(hist1[index]-hist2[index], 2)
 		denominator := hist1[index]
 		sum += numerator / denominator
@@ -38,6 +47,31 @@ func ChiSquare(hist1, hist2 []float64) (float64, error) {
 	return sum, nil
 }
 
+// SymmetricChiSquare calculates the distance between two histograms using
+// the symmetric chi square statistic. Bins that are empty in both histograms
+// (zero denominator) do not contribute to the distance.
+// x^2 = \sum_{i=1}^{n}\frac{(hist1_{i} - hist2_{i})^2}{hist1_{i} + hist2_{i}}
+// Reference:
+// Ahonen, Timo, Abdenour Hadid, and Matti Pietikäinen. "Face recognition with local binary patterns."
+func SymmetricChiSquare(hist1, hist2 []float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+
+	var sum float64
+	for index := 0; index < len(hist1); index++ {
+		denominator := hist1[index] + hist2[index]
+		if denominator == 0 {
+			continue
+		}
+		numerator := math.Pow(hist1[index]-hist2[index], 2)
+		sum += numerator / denominator
+	}
+	return sum, nil
+}
+
 // EuclideanDistance calculates the euclidean distance between two histograms
 // by the following formula:
 // D = \sqrt{\sum_{i=1}^{n}(hist1_{i} - hist2_{i})^2}
@@ -90,3 +124,374 @@ func AbsoluteValue(hist1, hist2 []float64) (float64, error) {
 	}
 	return sum, nil
 }
+
+// sum calculates the sum of all values of the histogram.
+func sum(hist []float64) float64 {
+	var total float64
+	for index := 0; index < len(hist); index++ {
+		total += hist[index]
+	}
+	return total
+}
+
+// Intersection calculates the histogram intersection between two histograms.
+// It is a similarity (the higher the value, the more similar the histograms are).
+// I = \sum_{i=1}^{n} \min(hist1_{i}, hist2_{i})
+// Reference:
+// http://docs.opencv.org/2.4/doc/tutorials/imgproc/histograms/histogram_comparison/histogram_comparison.html
+func Intersection(hist1, hist2 []float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+
+	var sum float64
+	for index := 0; index < len(hist1); index++ {
+		sum += math.Min(hist1[index], hist2[index])
+	}
+	return sum, nil
+}
+
+// IntersectionDistance calculates the distance between two histograms based on
+// the histogram intersection normalized by the smallest histogram sum.
+// D = 1 - \frac{\sum_{i=1}^{n} \min(hist1_{i}, hist2_{i})}{\min(\sum hist1, \sum hist2)}
+// If both histograms are empty the distance is 0.
+func IntersectionDistance(hist1, hist2 []float64) (float64, error) {
+
+	intersection, err := Intersection(hist1, hist2)
+	if err != nil {
+		return 0.0, err
+	}
+
+	denominator := math.Min(sum(hist1), sum(hist2))
+	if denominator == 0 {
+		if sum(hist1) == sum(hist2) {
+			return 0.0, nil
+		}
+		return 1.0, nil
+	}
+	return 1 - intersection/denominator, nil
+}
+
+// Correlation calculates the correlation between two histograms as in the OpenCV
+// compareHist function. It is a similarity in the [-1, 1] interval (1 means that
+// the histograms are perfectly correlated). If one of the histograms is constant
+// the correlation is 1 (same as OpenCV).
+// d = \frac{\sum_{i}(hist1_{i} - \bar{hist1})(hist2_{i} - \bar{hist2})}{\sqrt{\sum_{i}(hist1_{i} - \bar{hist1})^2 \sum_{i}(hist2_{i} - \bar{hist2})^2}}
+// Reference:
+// http://docs.opencv.org/2.4/doc/tutorials/imgproc/histograms/histogram_comparison/histogram_comparison.html
+func Correlation(hist1, hist2 []float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+
+	n := float64(len(hist1))
+	mean1 := sum(hist1) / n
+	mean2 := sum(hist2) / n
+
+	var numerator, variance1, variance2 float64
+	for index := 0; index < len(hist1); index++ {
+		numerator += (hist1[index] - mean1) * (hist2[index] - mean2)
+		variance1 += math.Pow(hist1[index]-mean1, 2)
+		variance2 += math.Pow(hist2[index]-mean2, 2)
+	}
+
+	denominator := variance1 * variance2
+	if math.Abs(denominator) <= epsilon {
+		return 1.0, nil
+	}
+	return numerator / math.Sqrt(denominator), nil
+}
+
+// CorrelationDistance calculates the distance between two histograms based on
+// the correlation. The distance is in the [0, 2] interval.
+// D = 1 - Correlation(hist1, hist2)
+func CorrelationDistance(hist1, hist2 []float64) (float64, error) {
+
+	correlation, err := Correlation(hist1, hist2)
+	if err != nil {
+		return 0.0, err
+	}
+	return 1 - correlation, nil
+}
+
+// bhattacharyyaCoefficient calculates the Bhattacharyya coefficient between the
+// two histograms normalized to sum 1 (as in the OpenCV compareHist function).
+// BC = \frac{\sum_{i=1}^{n} \sqrt{hist1_{i} hist2_{i}}}{\sqrt{\sum hist1 \sum hist2}}
+func bhattacharyyaCoefficient(hist1, hist2 []float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+
+	var coefficient float64
+	for index := 0; index < len(hist1); index++ {
+		coefficient += math.Sqrt(hist1[index] * hist2[index])
+	}
+
+	denominator := sum(hist1) * sum(hist2)
+	if denominator > 0 {
+		coefficient /= math.Sqrt(denominator)
+	}
+	return coefficient, nil
+}
+
+// HellingerDistance calculates the Hellinger distance between two histograms.
+// It is the metric called Bhattacharyya in the OpenCV compareHist function.
+// The distance is in the [0, 1] interval.
+// D = \sqrt{1 - BC(hist1, hist2)}
+// Reference:
+// http://docs.opencv.org/2.4/doc/tutorials/imgproc/histograms/histogram_comparison/histogram_comparison.html
+func HellingerDistance(hist1, hist2 []float64) (float64, error) {
+
+	coefficient, err := bhattacharyyaCoefficient(hist1, hist2)
+	if err != nil {
+		return 0.0, err
+	}
+	return math.Sqrt(math.Max(1-coefficient, 0)), nil
+}
+
+// BhattacharyyaDistance calculates the Bhattacharyya distance between two histograms.
+// It returns +Inf if the histograms do not overlap.
+// D = -\ln(BC(hist1, hist2))
+// Reference: https://en.wikipedia.org/wiki/Bhattacharyya_distance
+func BhattacharyyaDistance(hist1, hist2 []float64) (float64, error) {
+
+	coefficient, err := bhattacharyyaCoefficient(hist1, hist2)
+	if err != nil {
+		return 0.0, err
+	}
+	// The coefficient can be slightly higher than 1 due to rounding errors
+	return -math.Log(math.Min(coefficient, 1)), nil
+}
+
+// DefaultSmoothing is the smoothing used by the information-theoretic metrics
+// (KL divergence and log-likelihood) to avoid the logarithm of zero. It is relative
+// to the histogram mass: smoothing * sum(hist) / len(hist) is added to each bin, so
+// the raw and the normalized (Params.Normalize) histograms return the same distance.
+// LBP histograms are sparse, so a very small value would make the empty bins
+// dominate the distance.
+const DefaultSmoothing = 0.1
+
+// normalize converts the histogram to a probability distribution (sum 1) after
+// adding the smoothing (relative to the mean bin value) to each bin. If the
+// histogram is empty (sum 0) and the smoothing is positive, the uniform
+// distribution is returned.
+func normalize(hist []float64, smoothing float64) ([]float64, error) {
+	total := sum(hist)
+	if total <= 0 && smoothing <= 0 {
+		return nil, errors.New("Could not compare the histograms. The histogram sum is zero.")
+	}
+
+	distribution := make([]float64, len(hist))
+	if total <= 0 {
+		for index := range distribution {
+			distribution[index] = 1 / float64(len(hist))
+		}
+		return distribution, nil
+	}
+
+	offset := smoothing * total / float64(len(hist))
+	total += offset * float64(len(hist))
+	for index := 0; index < len(hist); index++ {
+		distribution[index] = (hist[index] + offset) / total
+	}
+	return distribution, nil
+}
+
+// KullbackLeiblerDivergence calculates the Kullback-Leibler divergence between
+// two histograms using the DefaultSmoothing. It is not symmetric.
+func KullbackLeiblerDivergence(hist1, hist2 []float64) (float64, error) {
+	return SmoothedKullbackLeiblerDivergence(hist1, hist2, DefaultSmoothing)
+}
+
+// SmoothedKullbackLeiblerDivergence calculates the Kullback-Leibler divergence
+// between two histograms normalized to probability distributions after adding
+// the smoothing to each bin (so empty bins do not return +Inf). The smoothing is
+// relative to the histogram mass (see DefaultSmoothing).
+// D = \sum_{i=1}^{n} p_{i} \ln\frac{p_{i}}{q_{i}}
+// Reference: https://en.wikipedia.org/wiki/Kullback%E2%80%93Leibler_divergence
+func SmoothedKullbackLeiblerDivergence(hist1, hist2 []float64, smoothing float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+
+	p, err := normalize(hist1, smoothing)
+	if err != nil {
+		return 0.0, err
+	}
+	q, err := normalize(hist2, smoothing)
+	if err != nil {
+		return 0.0, err
+	}
+
+	return kullbackLeibler(p, q), nil
+}
+
+// kullbackLeibler calculates the Kullback-Leibler divergence between two
+// probability distributions. Empty bins of p do not contribute to the sum.
+func kullbackLeibler(p, q []float64) float64 {
+	var sum float64
+	for index := 0; index < len(p); index++ {
+		if p[index] > 0 {
+			sum += p[index] * math.Log(p[index]/q[index])
+		}
+	}
+	return sum
+}
+
+// JensenShannonDivergence calculates the Jensen-Shannon divergence between two
+// histograms normalized to probability distributions. It is symmetric and
+// bounded by ln(2), so no smoothing is needed.
+// D = \frac{1}{2} KL(p, m) + \frac{1}{2} KL(q, m), where m = \frac{p + q}{2}
+// Reference: https://en.wikipedia.org/wiki/Jensen%E2%80%93Shannon_divergence
+func JensenShannonDivergence(hist1, hist2 []float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+
+	p, err := normalize(hist1, 0)
+	if err != nil {
+		return 0.0, err
+	}
+	q, err := normalize(hist2, 0)
+	if err != nil {
+		return 0.0, err
+	}
+
+	m := make([]float64, len(p))
+	for index := 0; index < len(p); index++ {
+		m[index] = (p[index] + q[index]) / 2
+	}
+
+	return kullbackLeibler(p, m)/2 + kullbackLeibler(q, m)/2, nil
+}
+
+// LogLikelihood calculates the log-likelihood statistic between the sample
+// histogram (hist1) and the model histogram (hist2), both normalized to
+// probability distributions. The DefaultSmoothing is added to the model bins.
+// It is not symmetric and it is not zero for equal histograms (it is the
+// cross-entropy), but the lower the value, the more similar the histograms are.
+// L = -\sum_{i=1}^{n} hist1_{i} \ln hist2_{i}
+// Reference:
+// Ahonen, Timo, Abdenour Hadid, and Matti Pietikäinen. "Face recognition with local binary patterns."
+func LogLikelihood(hist1, hist2 []float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+
+	sample, err := normalize(hist1, 0)
+	if err != nil {
+		return 0.0, err
+	}
+	model, err := normalize(hist2, DefaultSmoothing)
+	if err != nil {
+		return 0.0, err
+	}
+
+	var sum float64
+	for index := 0; index < len(sample); index++ {
+		if sample[index] > 0 {
+			sum -= sample[index] * math.Log(model[index])
+		}
+	}
+	return sum, nil
+}
+
+// CosineDistance calculates the cosine distance between two histograms.
+// The distance is in the [0, 2] interval (0 for histograms with the same direction).
+// If both histograms are zero the distance is 0, if only one is zero it is 1.
+// D = 1 - \frac{\sum_{i=1}^{n} hist1_{i} hist2_{i}}{\sqrt{\sum_{i=1}^{n} hist1_{i}^2} \sqrt{\sum_{i=1}^{n} hist2_{i}^2}}
+// Reference: https://en.wikipedia.org/wiki/Cosine_similarity
+func CosineDistance(hist1, hist2 []float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+
+	var dot, norm1, norm2 float64
+	for index := 0; index < len(hist1); index++ {
+		dot += hist1[index] * hist2[index]
+		norm1 += hist1[index] * hist1[index]
+		norm2 += hist2[index] * hist2[index]
+	}
+
+	if norm1 == 0 || norm2 == 0 {
+		if norm1 == norm2 {
+			return 0.0, nil
+		}
+		return 1.0, nil
+	}
+	return 1 - dot/(math.Sqrt(norm1)*math.Sqrt(norm2)), nil
+}
+
+// DiagonalMahalanobisDistance calculates the Mahalanobis distance between two
+// histograms using a diagonal covariance matrix (the variance of each bin).
+// All variances must be positive.
+// D = \sqrt{\sum_{i=1}^{n} \frac{(hist1_{i} - hist2_{i})^2}{\sigma_{i}^2}}
+// Reference: https://en.wikipedia.org/wiki/Mahalanobis_distance
+func DiagonalMahalanobisDistance(hist1, hist2, variances []float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+	if len(variances) != len(hist1) {
+		return 0.0, errors.New("Could not compare the histograms. The variances have a different size.")
+	}
+
+	var sum float64
+	for index := 0; index < len(hist1); index++ {
+		if variances[index] <= 0 {
+			return 0.0, errors.New("Could not compare the histograms. The variances should be positive.")
+		}
+		sum += math.Pow(hist1[index]-hist2[index], 2) / variances[index]
+	}
+	return math.Sqrt(sum), nil
+}
+
+// MahalanobisDistance calculates the Mahalanobis distance between two histograms
+// using the inverse of the covariance matrix passed by parameter.
+// D = \sqrt{(hist1 - hist2)^T S^{-1} (hist1 - hist2)}
+// Reference: https://en.wikipedia.org/wiki/Mahalanobis_distance
+func MahalanobisDistance(hist1, hist2 []float64, inverseCovariance [][]float64) (float64, error) {
+
+	// Check the histogram sizes
+	if err := checkHistograms(hist1, hist2); err != nil {
+		return 0.0, err
+	}
+	if len(inverseCovariance) != len(hist1) {
+		return 0.0, errors.New("Could not compare the histograms. The covariance matrix has a different size.")
+	}
+
+	difference := make([]float64, len(hist1))
+	for index := 0; index < len(hist1); index++ {
+		difference[index] = hist1[index] - hist2[index]
+	}
+
+	var sum float64
+	for row := 0; row < len(difference); row++ {
+		if len(inverseCovariance[row]) != len(hist1) {
+			return 0.0, errors.New("Could not compare the histograms. The covariance matrix has a different size.")
+		}
+		var value float64
+		for col := 0; col < len(difference); col++ {
+			value += inverseCovariance[row][col] * difference[col]
+		}
+		sum += difference[row] * value
+	}
+
+	// The sum should never be negative, but it can be due to rounding errors
+	return math.Sqrt(math.Max(sum, 0)), nil
+}
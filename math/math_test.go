@@ -19,12 +19,15 @@ func TestChiSquare(t *testing.T) {
 		{[]float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, []float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, 64.00000000000001},
 		{[]float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 0.0},
 		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, -1.0},
+		{[]float64{0, 2, 0, 0, 0, 0, 0, 0, 3}, []float64{0, 1, 0, 0, 0, 0, 0, 0, 3}, 0.5},
+		{[]float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, 0.0},
 	}
 
 	// Test with all values in the table
 	for _, pair := range tTable {
 		distance, err := ChiSquare(pair.hist1, pair.hist2)
 		assert.Nil(t, err)
+		assert.False(t, math.IsNaN(distance))
 		if pair.distance >= 0 {
 			assert.Equal(t, pair.distance, distance)
 		} else {
@@ -33,6 +36,35 @@ func TestChiSquare(t *testing.T) {
 	}
 }
 
+func TestSymmetricChiSquare(t *testing.T) {
+	// Table tests
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, 24.0},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 57.6},
+		{[]float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, []float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, 57.6},
+		{[]float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, 25.0},
+		{[]float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, 0.0},
+		{[]float64{0, 2, 0, 0, 0, 0, 0, 0, 3}, []float64{0, 0, 0, 0, 0, 0, 0, 0, 1}, 3.0},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := SymmetricChiSquare(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.False(t, math.IsNaN(distance))
+		assert.False(t, math.IsInf(distance, 0))
+		assert.InDelta(t, pair.distance, distance, 1e-9)
+	}
+
+	_, err := SymmetricChiSquare([]float64{}, []float64{1})
+	assert.NotNil(t, err)
+}
+
 func TestEuclideanDistance(t *testing.T) {
 	// Table tests
 	var tTable = []struct {
@@ -98,3 +130,275 @@ func TestAbsoluteValue(t *testing.T) {
 		assert.Equal(t, pair.distance, distance)
 	}
 }
+
+func TestIntersection(t *testing.T) {
+	// Table tests (reference values calculated as in the OpenCV compareHist function)
+	var tTable = []struct {
+		hist1        []float64
+		hist2        []float64
+		intersection float64
+		distance     float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, 25.0, 0.4444444444444444},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 9.0, 0.0},
+		{[]float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 81.0, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, 0.0, 1.0},
+		{[]float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, 0.0, 0.0},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		intersection, err := Intersection(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.Equal(t, pair.intersection, intersection)
+
+		distance, err := IntersectionDistance(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-12)
+	}
+
+	_, err := IntersectionDistance([]float64{1, 2}, []float64{1})
+	assert.NotNil(t, err)
+}
+
+func TestCorrelation(t *testing.T) {
+	// Table tests (reference values calculated as in the OpenCV compareHist function)
+	var tTable = []struct {
+		hist1       []float64
+		hist2       []float64
+		correlation float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, -1.0},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 1.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, -0.6576670522058207},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{2, 3, 4, 5, 6, 7, 8, 9, 10}, 1.0},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		correlation, err := Correlation(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.correlation, correlation, 1e-12)
+
+		distance, err := CorrelationDistance(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.InDelta(t, 1-pair.correlation, distance, 1e-12)
+	}
+
+	_, err := CorrelationDistance([]float64{}, []float64{})
+	assert.NotNil(t, err)
+}
+
+func TestHellingerDistance(t *testing.T) {
+	// Table tests (reference values calculated as the Bhattacharyya metric of OpenCV)
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, 0.3954432011175875},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, 1.0},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{2, 3, 4, 5, 6, 7, 8, 9, 10}, 0.04070926319888617},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := HellingerDistance(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-7)
+	}
+
+	_, err := HellingerDistance([]float64{1}, []float64{})
+	assert.NotNil(t, err)
+}
+
+func TestBhattacharyyaDistance(t *testing.T) {
+	// Table tests
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, 0.17004758152850868},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, -1.0},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{2, 3, 4, 5, 6, 7, 8, 9, 10}, 0.0016586188582886432},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := BhattacharyyaDistance(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		if pair.distance >= 0 {
+			assert.InDelta(t, pair.distance, distance, 1e-12)
+		} else {
+			assert.Equal(t, true, math.IsInf(distance, 1))
+		}
+	}
+}
+
+func TestKullbackLeiblerDivergence(t *testing.T) {
+	// Table tests
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, 0.5230964824678295},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, 2.8530302037802433},
+		{[]float64{0, 2, 0, 0, 0, 0, 0, 0, 3}, []float64{0, 0, 0, 0, 0, 0, 0, 0, 1}, 1.0697981322949033},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := KullbackLeiblerDivergence(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-9)
+	}
+
+	// Without smoothing the empty bins return +Inf
+	distance, err := SmoothedKullbackLeiblerDivergence([]float64{1, 1}, []float64{1, 0}, 0)
+	assert.Nil(t, err)
+	assert.True(t, math.IsInf(distance, 1))
+
+	_, err = KullbackLeiblerDivergence([]float64{0, 0}, []float64{1, 0})
+	assert.Nil(t, err)
+
+	_, err = SmoothedKullbackLeiblerDivergence([]float64{0, 0}, []float64{1, 0}, 0)
+	assert.NotNil(t, err)
+}
+
+func TestJensenShannonDivergence(t *testing.T) {
+	// Table tests
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, 0.14738385694355452},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, math.Ln2},
+		{[]float64{0, 2, 0, 0, 0, 0, 0, 0, 3}, []float64{0, 0, 0, 0, 0, 0, 0, 0, 1}, 0.1638965900335596},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := JensenShannonDivergence(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-12)
+
+		// The divergence is symmetric
+		reverse, err := JensenShannonDivergence(pair.hist2, pair.hist1)
+		assert.Nil(t, err)
+		assert.InDelta(t, distance, reverse, 1e-12)
+	}
+
+	_, err := JensenShannonDivergence([]float64{0, 0}, []float64{1, 0})
+	assert.NotNil(t, err)
+}
+
+func TestLogLikelihood(t *testing.T) {
+	// Table tests
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, 2.627297128594283},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 2.1972245773362196},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, 4.595119850134589},
+		{[]float64{0, 2, 0, 0, 0, 0, 0, 0, 3}, []float64{0, 0, 0, 0, 0, 0, 0, 0, 1}, 1.88860414622448},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := LogLikelihood(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-9)
+	}
+
+	_, err := LogLikelihood([]float64{1}, []float64{1, 2})
+	assert.NotNil(t, err)
+}
+
+func TestSmoothingScale(t *testing.T) {
+	// The smoothing is relative to the histogram mass, so the raw and the
+	// normalized (sum 1) histograms return the same distance
+	raw1 := []float64{1, 4, 5, 4, 1, 0, 0, 0, 0}
+	raw2 := []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}
+	normalized1 := []float64{1.0 / 15, 4.0 / 15, 5.0 / 15, 4.0 / 15, 1.0 / 15, 0, 0, 0, 0}
+	normalized2 := []float64{0, 0, 0, 0, 0, 0.1, 0.3, 0.4, 0.2}
+
+	for _, distance := range []func(hist1, hist2 []float64) (float64, error){KullbackLeiblerDivergence, LogLikelihood} {
+		rawDistance, err := distance(raw1, raw2)
+		assert.Nil(t, err)
+		normalizedDistance, err := distance(normalized1, normalized2)
+		assert.Nil(t, err)
+		assert.InDelta(t, rawDistance, normalizedDistance, 1e-9)
+
+		// The normalized histograms are not smoothed to the uniform distribution
+		sameDistance, err := distance(normalized1, normalized1)
+		assert.Nil(t, err)
+		assert.True(t, normalizedDistance > sameDistance+1)
+	}
+}
+
+func TestCosineDistance(t *testing.T) {
+	// Table tests
+	var tTable = []struct {
+		hist1    []float64
+		hist2    []float64
+		distance float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []float64{9, 8, 7, 6, 5, 4, 3, 2, 1}, 0.42105263157894746},
+		{[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, []float64{9, 9, 9, 9, 9, 9, 9, 9, 9}, 0.0},
+		{[]float64{1, 4, 5, 4, 1, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, 1.0},
+		{[]float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 1, 3, 4, 2}, 1.0},
+		{[]float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, []float64{0, 0, 0, 0, 0, 0, 0, 0, 0}, 0.0},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		distance, err := CosineDistance(pair.hist1, pair.hist2)
+		assert.Nil(t, err)
+		assert.InDelta(t, pair.distance, distance, 1e-12)
+	}
+
+	_, err := CosineDistance([]float64{1}, []float64{1, 2})
+	assert.NotNil(t, err)
+}
+
+func TestDiagonalMahalanobisDistance(t *testing.T) {
+	distance, err := DiagonalMahalanobisDistance([]float64{1, 2, 3}, []float64{3, 2, 0}, []float64{1, 4, 9})
+	assert.Nil(t, err)
+	assert.InDelta(t, 2.23606797749979, distance, 1e-12)
+
+	// With unit variances it is the euclidean distance
+	distance, err = DiagonalMahalanobisDistance([]float64{1, 2, 3}, []float64{3, 2, 0}, []float64{1, 1, 1})
+	assert.Nil(t, err)
+	expected, _ := EuclideanDistance([]float64{1, 2, 3}, []float64{3, 2, 0})
+	assert.Equal(t, expected, distance)
+
+	_, err = DiagonalMahalanobisDistance([]float64{1, 2, 3}, []float64{3, 2, 0}, []float64{1, 0, 1})
+	assert.NotNil(t, err)
+
+	_, err = DiagonalMahalanobisDistance([]float64{1, 2, 3}, []float64{3, 2, 0}, []float64{1, 1})
+	assert.NotNil(t, err)
+}
+
+func TestMahalanobisDistance(t *testing.T) {
+	inverseCovariance := [][]float64{{2, 1, 0}, {1, 2, 0}, {0, 0, 1}}
+
+	distance, err := MahalanobisDistance([]float64{1, 2, 3}, []float64{3, 2, 0}, inverseCovariance)
+	assert.Nil(t, err)
+	assert.InDelta(t, 4.123105625617661, distance, 1e-12)
+
+	_, err = MahalanobisDistance([]float64{1, 2, 3}, []float64{3, 2, 0}, inverseCovariance[:2])
+	assert.NotNil(t, err)
+
+	inverseCovariance[1] = []float64{1, 2}
+	_, err = MahalanobisDistance([]float64{1, 2, 3}, []float64{3, 2, 0}, inverseCovariance)
+	assert.NotNil(t, err)
+}
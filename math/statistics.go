@@ -0,0 +1,148 @@
+package math
+
+import (
+	"errors"
+	"math"
+)
+
+// checkSamples check if the samples are not empty and have the same size.
+func checkSamples(samples [][]float64) error {
+	if len(samples) == 0 || len(samples[0]) == 0 {
+		return errors.New("Could not calculate the statistics. The samples are empty.")
+	}
+	for index := 1; index < len(samples); index++ {
+		if len(samples[index]) != len(samples[0]) {
+			return errors.New("Could not calculate the statistics. The samples have different sizes.")
+		}
+	}
+	return nil
+}
+
+// Mean calculates the mean of each bin of the samples (histograms).
+func Mean(samples [][]float64) ([]float64, error) {
+	if err := checkSamples(samples); err != nil {
+		return nil, err
+	}
+
+	mean := make([]float64, len(samples[0]))
+	for _, sample := range samples {
+		for index := 0; index < len(sample); index++ {
+			mean[index] += sample[index]
+		}
+	}
+	for index := 0; index < len(mean); index++ {
+		mean[index] /= float64(len(samples))
+	}
+	return mean, nil
+}
+
+// Variances calculates the (population) variance of each bin of the samples.
+func Variances(samples [][]float64) ([]float64, error) {
+	mean, err := Mean(samples)
+	if err != nil {
+		return nil, err
+	}
+
+	variances := make([]float64, len(mean))
+	for _, sample := range samples {
+		for index := 0; index < len(sample); index++ {
+			variances[index] += math.Pow(sample[index]-mean[index], 2)
+		}
+	}
+	for index := 0; index < len(variances); index++ {
+		variances[index] /= float64(len(samples))
+	}
+	return variances, nil
+}
+
+// Covariance calculates the (population) covariance matrix of the samples.
+func Covariance(samples [][]float64) ([][]float64, error) {
+	mean, err := Mean(samples)
+	if err != nil {
+		return nil, err
+	}
+
+	size := len(mean)
+	covariance := make([][]float64, size)
+	for row := 0; row < size; row++ {
+		covariance[row] = make([]float64, size)
+	}
+
+	for _, sample := range samples {
+		for row := 0; row < size; row++ {
+			difference := sample[row] - mean[row]
+			if difference == 0 {
+				continue
+			}
+			for col := row; col < size; col++ {
+				covariance[row][col] += difference * (sample[col] - mean[col])
+			}
+		}
+	}
+
+	// The matrix is symmetric, so only the upper triangle was calculated
+	for row := 0; row < size; row++ {
+		for col := row; col < size; col++ {
+			covariance[row][col] /= float64(len(samples))
+			covariance[col][row] = covariance[row][col]
+		}
+	}
+	return covariance, nil
+}
+
+// Inverse calculates the inverse of a square matrix using the Gauss-Jordan
+// elimination with partial pivoting. It returns an error if the matrix is singular.
+func Inverse(matrix [][]float64) ([][]float64, error) {
+	size := len(matrix)
+	if size == 0 {
+		return nil, errors.New("Could not invert the matrix. The matrix is empty.")
+	}
+
+	// Create the augmented matrix [matrix | identity]
+	augmented := make([][]float64, size)
+	for row := 0; row < size; row++ {
+		if len(matrix[row]) != size {
+			return nil, errors.New("Could not invert the matrix. The matrix is not square.")
+		}
+		augmented[row] = make([]float64, 2*size)
+		copy(augmented[row], matrix[row])
+		augmented[row][size+row] = 1
+	}
+
+	for col := 0; col < size; col++ {
+		// Find the pivot (the highest absolute value in the column)
+		pivot := col
+		for row := col + 1; row < size; row++ {
+			if math.Abs(augmented[row][col]) > math.Abs(augmented[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(augmented[pivot][col]) <= epsilon {
+			return nil, errors.New("Could not invert the matrix. The matrix is singular.")
+		}
+		augmented[col], augmented[pivot] = augmented[pivot], augmented[col]
+
+		// Normalize the pivot row
+		value := augmented[col][col]
+		for index := 0; index < 2*size; index++ {
+			augmented[col][index] /= value
+		}
+
+		// Eliminate the column from the other rows
+		for row := 0; row < size; row++ {
+			factor := augmented[row][col]
+			if row == col || factor == 0 {
+				continue
+			}
+			for index := 0; index < 2*size; index++ {
+				augmented[row][index] -= factor * augmented[col][index]
+			}
+		}
+	}
+
+	inverse := make([][]float64, size)
+	for row := 0; row < size; row++ {
+		inverse[row] = augmented[row][size:]
+	}
+	return inverse, nil
+}
@@ -0,0 +1,75 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMean(t *testing.T) {
+	samples := [][]float64{{1, 2, 3}, {3, 2, 1}, {2, 2, 8}}
+
+	mean, err := Mean(samples)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{2, 2, 4}, mean)
+
+	_, err = Mean(nil)
+	assert.NotNil(t, err)
+
+	_, err = Mean([][]float64{{1, 2}, {1}})
+	assert.NotNil(t, err)
+}
+
+func TestVariances(t *testing.T) {
+	samples := [][]float64{{1, 2, 3}, {3, 2, 1}}
+
+	variances, err := Variances(samples)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{1, 0, 1}, variances)
+
+	_, err = Variances([][]float64{})
+	assert.NotNil(t, err)
+}
+
+func TestCovariance(t *testing.T) {
+	samples := [][]float64{{1, 2, 3}, {3, 2, 1}}
+
+	expectedCovariance := [][]float64{{1, 0, -1}, {0, 0, 0}, {-1, 0, 1}}
+
+	covariance, err := Covariance(samples)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedCovariance, covariance)
+
+	_, err = Covariance([][]float64{{}})
+	assert.NotNil(t, err)
+}
+
+func TestInverse(t *testing.T) {
+	matrix := [][]float64{{4, 7}, {2, 6}}
+	expectedInverse := [][]float64{{0.6, -0.7}, {-0.2, 0.4}}
+
+	inverse, err := Inverse(matrix)
+	assert.Nil(t, err)
+	for row := 0; row < len(expectedInverse); row++ {
+		for col := 0; col < len(expectedInverse[row]); col++ {
+			assert.InDelta(t, expectedInverse[row][col], inverse[row][col], 1e-12)
+		}
+	}
+
+	// The input matrix should not be changed
+	assert.Equal(t, [][]float64{{4, 7}, {2, 6}}, matrix)
+
+	// Pivoting is needed (zero in the diagonal)
+	inverse, err = Inverse([][]float64{{0, 1}, {1, 0}})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]float64{{0, 1}, {1, 0}}, inverse)
+
+	_, err = Inverse([][]float64{{1, 2}, {2, 4}})
+	assert.NotNil(t, err)
+
+	_, err = Inverse([][]float64{{1, 2}, {2}})
+	assert.NotNil(t, err)
+
+	_, err = Inverse(nil)
+	assert.NotNil(t, err)
+}
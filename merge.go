@@ -0,0 +1,317 @@
+package lbph
+
+import (
+	"errors"
+	"image"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+// MergePolicy type defines how the Merge function handles the labels found in
+// both models (label conflicts).
+type MergePolicy int
+
+const (
+	// MergeKeepBoth keeps the samples of both models (e.g. the same person enrolled in two sites).
+	MergeKeepBoth MergePolicy = iota
+	// MergeKeepExisting keeps only the samples of the trained algorithm.
+	MergeKeepExisting
+	// MergeReplace replaces the samples of the trained algorithm by the samples of the merged model.
+	MergeReplace
+	// MergeReject rejects the merge if a label is found in both models.
+	MergeReject
+)
+
+// checkCompatible function checks if the decoded model can be merged into the
+// training data: same LBPH parameters, metric, image size and mask (the ones used
+// to train each model), and both models trained using sample records (or none of them).
+func checkCompatible(other *TrainingData) error {
+	if !equalParams(trainingData.Params, lbphParams) {
+		return errors.New("The LBPH parameters have changed since the algorithm was trained")
+	}
+	if !equalParams(trainingData.Params, other.Params) {
+		return errors.New("The models have different LBPH parameters")
+	}
+	if trainingData.SelectedMetric == nil || other.SelectedMetric == nil ||
+		trainingData.SelectedMetric.Name() != other.SelectedMetric.Name() {
+		return errors.New("The models use different metrics")
+	}
+
+	// The size of the training images is unknown (0) for the imported models.
+	if trainingData.Width != 0 && other.Width != 0 &&
+		(trainingData.Width != other.Width || trainingData.Height != other.Height) {
+		return errors.New("The models were trained using images of different sizes")
+	}
+
+	// The samples of the model without records would have no ID
+	if (trainingData.Samples == nil) != (other.Samples == nil) {
+		return errors.New("Only one of the models was trained using sample records")
+	}
+
+	if !equalMasks(trainingData.Mask, other.Mask) {
+		return errors.New("The models use different masks")
+	}
+	return nil
+}
+
+// Merge function reads a model written by the Save function (e.g. trained in
+// another site) and merges it into the trained algorithm. Both models must have
+// the same LBPH parameters, metric, image size and mask, and the sample records
+// can not be mixed (both models or none of them must have them) and the IDs of the
+// merged samples must be unique. The labels found in both
+// models are handled based on the policy. The metric parameters and the prototypes
+// are recalculated, but the calibration of the trained algorithm is kept. The images
+// are only kept if RetainUpdateImages is true and they are available in both models.
+func Merge(r io.Reader, policy MergePolicy) error {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return errors.New("The algorithm was not trained yet")
+	}
+	if policy < MergeKeepBoth || policy > MergeReject {
+		return errors.New("Invalid policy passed to the Merge function")
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m, err := unmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	other, err := m.decode()
+	if err != nil {
+		return err
+	}
+	if err := checkCompatible(other); err != nil {
+		return err
+	}
+	histograms, err := trainingData.getHistograms()
+	if err != nil {
+		return err
+	}
+	otherHistograms, err := other.getHistograms()
+	if err != nil {
+		return err
+	}
+	if len(histograms[0]) != len(otherHistograms[0]) {
+		return errors.New("The models have histograms of different sizes")
+	}
+
+	// Find the labels found in both models
+	existing := make(map[string]bool)
+	for _, label := range trainingData.Labels {
+		existing[label] = true
+	}
+	conflicts := make(map[string]bool)
+	for _, label := range other.Labels {
+		if existing[label] {
+			if policy == MergeReject {
+				return errors.New("The label '" + label + "' is found in both models")
+			}
+			conflicts[label] = true
+		}
+	}
+
+	// The images are only kept if they are retained and available in both models.
+	hasImages := RetainUpdateImages && len(trainingData.Images) == len(trainingData.Labels) &&
+		len(other.Images) == len(other.Labels)
+	hasSamples := trainingData.Samples != nil
+
+	var mergedImages []image.Image
+	var mergedLabels []string
+	var mergedHistograms [][]float64
+	var mergedSamples []Sample
+
+	appendData := func(data *TrainingData, histograms [][]float64, keep func(label string) bool) {
+		for index, label := range data.Labels {
+			if !keep(label) {
+				continue
+			}
+			if hasImages {
+				mergedImages = append(mergedImages, data.Images[index])
+			}
+			mergedLabels = append(mergedLabels, label)
+			mergedHistograms = append(mergedHistograms, histograms[index])
+			if hasSamples {
+				mergedSamples = append(mergedSamples, data.Samples[index])
+			}
+		}
+	}
+	appendData(trainingData, histograms, func(label string) bool {
+		return policy != MergeReplace || !conflicts[label]
+	})
+	appendData(other, otherHistograms, func(label string) bool {
+		return policy != MergeKeepExisting || !conflicts[label]
+	})
+
+	// The IDs of the merged samples must be unique (e.g. MergeKeepBoth)
+	if err := checkUniqueIDs(mergedSamples); err != nil {
+		return err
+	}
+
+	width, height := trainingData.Width, trainingData.Height
+	if width == 0 {
+		width, height = other.Width, other.Height
+	}
+	mergedData, err := newTrainingData(trainingData, mergedImages, mergedLabels, mergedHistograms, width, height)
+	if err != nil {
+		return err
+	}
+
+	// Keep the calibration fitted before the merge.
+	mergedData.Calibration = trainingData.Calibration
+	mergedData.Samples = mergedSamples
+	trainingData = mergedData
+
+	return nil
+}
+
+// ShardLabels function returns the shard (between 0 and shards-1) of each label of
+// the training data, as used by the SaveShards function. The sorted labels are assigned
+// in round-robin, so the assignment is deterministic and the shards have a similar
+// number of labels (none of them is empty).
+func ShardLabels(shards int) (map[string]int, error) {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return nil, errors.New("The algorithm was not trained yet")
+	}
+	if shards <= 0 {
+		return nil, errors.New("The number of shards should be higher than 0")
+	}
+
+	labels := GetLabels()
+	if len(labels) < shards {
+		return nil, errors.New("The training data has less labels than shards (use less shards)")
+	}
+
+	assigned := make(map[string]int, len(labels))
+	for index, label := range labels {
+		assigned[label] = index % shards
+	}
+	return assigned, nil
+}
+
+// newShard function returns the training data of a shard: the samples of the
+// labels passed by parameter. The fitted metric, the calibration and the prototypes
+// are not recalculated, so the distances of all shards can be compared, and the mask
+// is kept, so the shards extract the probe histograms as the model.
+func newShard(labels map[string]bool) (*TrainingData, error) {
+	shard := &TrainingData{
+		Params:         trainingData.Params,
+		Width:          trainingData.Width,
+		Height:         trainingData.Height,
+		SelectedMetric: trainingData.SelectedMetric,
+		Metric:         trainingData.Metric,
+		Calibration:    trainingData.Calibration,
+		Prototypes:     trainingData.Prototypes,
+		Mask:           trainingData.Mask,
+	}
+
+	// The histograms of the galleries are decoded
+	var histograms [][]float64
+	if trainingData.CompactHistograms == nil {
+		var err error
+		if histograms, err = trainingData.getHistograms(); err != nil {
+			return nil, err
+		}
+	}
+	hasImages := len(trainingData.Images) == len(trainingData.Labels)
+
+	for index, label := range trainingData.Labels {
+		if !labels[label] {
+			continue
+		}
+		if hasImages {
+			shard.Images = append(shard.Images, trainingData.Images[index])
+		}
+		shard.Labels = append(shard.Labels, label)
+		if histograms != nil {
+			shard.Histograms = append(shard.Histograms, histograms[index])
+		} else {
+			shard.CompactHistograms = append(shard.CompactHistograms, trainingData.CompactHistograms[index])
+		}
+		if trainingData.Samples != nil {
+			shard.Samples = append(shard.Samples, trainingData.Samples[index])
+		}
+	}
+
+	// The prototypes are calculated per label
+	for index, label := range trainingData.PrototypeLabels {
+		if labels[label] {
+			shard.PrototypeLabels = append(shard.PrototypeLabels, label)
+			shard.PrototypeHistograms = append(shard.PrototypeHistograms, trainingData.PrototypeHistograms[index])
+		}
+	}
+	return shard, nil
+}
+
+// SaveShards function splits the training data by label into len(writers) shards
+// (see the ShardLabels function) and writes each shard to its writer using the Save
+// format, so the shards can be searched in different nodes (see the MergePredictions
+// function). All samples of a label are stored in the same shard and the fitted metric,
+// the calibration and the prototypes are not recalculated. Nothing is written if
+// there are less labels than writers.
+func SaveShards(writers []io.Writer) error {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return errors.New("The algorithm was not trained yet")
+	}
+	if len(writers) == 0 {
+		return errors.New("At least one writer should be passed to the SaveShards function")
+	}
+
+	assigned, err := ShardLabels(len(writers))
+	if err != nil {
+		return err
+	}
+	shardLabels := make([]map[string]bool, len(writers))
+	for index := range shardLabels {
+		shardLabels[index] = make(map[string]bool)
+	}
+	for label, shard := range assigned {
+		shardLabels[shard][label] = true
+	}
+
+	// Encode all shards before writing them
+	var encoded [][]byte
+	for _, labels := range shardLabels {
+		shard, err := newShard(labels)
+		if err != nil {
+			return err
+		}
+		m, err := newModel(shard)
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, m.marshalBinary())
+	}
+
+	for index, w := range writers {
+		if _, err := w.Write(encoded[index]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergePredictions function merges the predictions returned by the PredictTopK
+// function in different shards and returns the K closest ones (sorted by distance).
+// The Index of each prediction refers to the training data of its shard.
+func MergePredictions(k int, results ...[]Prediction) []Prediction {
+	var predictions []Prediction
+	for _, result := range results {
+		predictions = append(predictions, result...)
+	}
+	sort.SliceStable(predictions, func(i, j int) bool {
+		return predictions[i].Distance < predictions[j].Distance
+	})
+	if k >= 0 && k < len(predictions) {
+		predictions = predictions[:k]
+	}
+	return predictions
+}
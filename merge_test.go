@@ -0,0 +1,247 @@
+package lbph
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kelvins/lbph/lbp"
+	"github.com/kelvins/lbph/mask"
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+	}()
+
+	Init(Params{})
+	assert.NotNil(t, Merge(&bytes.Buffer{}, MergeKeepBoth))
+
+	// Train the models of two sites
+	images := trainSamples(t)
+	var site1, site2 bytes.Buffer
+	assert.Nil(t, TrainSamples(images[:3], []Sample{
+		{ID: 1, Label: "rocks"}, {ID: 2, Label: "grass"}, {ID: 3, Label: "wood"},
+	}))
+	assert.Nil(t, Save(&site1))
+	assert.Nil(t, TrainSamples(images[3:], []Sample{{ID: 4, Label: "wood"}, {ID: 5, Label: "stone"}}))
+	assert.Nil(t, Save(&site2))
+
+	testCases := []struct {
+		policy MergePolicy
+		labels []string
+		ids    []int64
+	}{
+		{MergeKeepBoth, []string{"rocks", "grass", "wood", "wood", "stone"}, []int64{1, 2, 3, 4, 5}},
+		{MergeKeepExisting, []string{"rocks", "grass", "wood", "stone"}, []int64{1, 2, 3, 5}},
+		{MergeReplace, []string{"rocks", "grass", "wood", "stone"}, []int64{1, 2, 4, 5}},
+	}
+
+	for _, testCase := range testCases {
+		assert.Nil(t, Load(bytes.NewReader(site1.Bytes())))
+		assert.Nil(t, Merge(bytes.NewReader(site2.Bytes()), testCase.policy))
+
+		trainData := GetTrainingData()
+		assert.Equal(t, testCase.labels, trainData.Labels)
+		var ids []int64
+		for _, sample := range trainData.Samples {
+			ids = append(ids, sample.ID)
+		}
+		assert.Equal(t, testCase.ids, ids)
+
+		label, distance, err := Predict(images[4])
+		assert.Nil(t, err)
+		assert.Equal(t, "stone", label)
+		assert.Equal(t, 0.0, distance)
+	}
+
+	// Label conflicts and invalid policies are rejected
+	assert.Nil(t, Load(bytes.NewReader(site1.Bytes())))
+	err := Merge(bytes.NewReader(site2.Bytes()), MergeReject)
+	assert.EqualError(t, err, "The label 'wood' is found in both models")
+	err = Merge(bytes.NewReader(site2.Bytes()), MergePolicy(10))
+	assert.NotNil(t, err)
+	err = Merge(bytes.NewReader(site2.Bytes()[:10]), MergeKeepBoth)
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, len(GetTrainingData().Labels))
+
+	// The IDs of the merged samples must be unique
+	var site3 bytes.Buffer
+	assert.Nil(t, TrainSamples(images[4:], []Sample{{ID: 1, Label: "rocks"}}))
+	assert.Nil(t, Save(&site3))
+	assert.Nil(t, Load(bytes.NewReader(site1.Bytes())))
+	err = Merge(bytes.NewReader(site3.Bytes()), MergeKeepBoth)
+	assert.EqualError(t, err, "The sample ID 1 is duplicated")
+	assert.Equal(t, 3, len(GetTrainingData().Labels))
+	assert.Nil(t, Merge(bytes.NewReader(site3.Bytes()), MergeReplace))
+	assert.Equal(t, []string{"grass", "wood", "rocks"}, GetTrainingData().Labels)
+}
+
+func TestMergeIncompatible(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+	}()
+
+	images := trainSamples(t)
+	var base bytes.Buffer
+	assert.Nil(t, Save(&base))
+
+	testCases := []struct {
+		params Params
+		metric metric.Metric
+		err    string
+	}{
+		{Params{GridX: 4, GridY: 4}, metric.EuclideanDistance, "The models have different LBPH parameters"},
+		{Params{}, metric.ChiSquare, "The models use different metrics"},
+	}
+
+	for _, testCase := range testCases {
+		Init(testCase.params)
+		Metric = testCase.metric
+		assert.Nil(t, Train(images, []string{"a", "b", "c", "d", "e"}))
+		var other bytes.Buffer
+		assert.Nil(t, Save(&other))
+
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+		assert.Nil(t, Load(bytes.NewReader(base.Bytes())))
+		err := Merge(&other, MergeKeepBoth)
+		assert.EqualError(t, err, testCase.err)
+	}
+
+	// The sample records can not be mixed with the labels
+	assert.Nil(t, TrainSamples(images[:2], []Sample{{ID: 0, Label: "a"}, {ID: 1, Label: "b"}}))
+	var withSamples bytes.Buffer
+	assert.Nil(t, Save(&withSamples))
+	assert.Nil(t, Load(bytes.NewReader(base.Bytes())))
+	err := Merge(bytes.NewReader(withSamples.Bytes()), MergeKeepBoth)
+	assert.EqualError(t, err, "Only one of the models was trained using sample records")
+	assert.Nil(t, GetTrainingData().Samples)
+
+	assert.Nil(t, Load(bytes.NewReader(withSamples.Bytes())))
+	err = Merge(bytes.NewReader(base.Bytes()), MergeKeepBoth)
+	assert.EqualError(t, err, "Only one of the models was trained using sample records")
+	removed, err := RemoveSamplesByID(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+}
+
+func TestMergeKeepsSettings(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+		Mask = nil
+	}()
+	Metric = metric.ChiSquare
+
+	images := trainSamples(t)
+	var other bytes.Buffer
+	assert.Nil(t, Save(&other))
+
+	// The metric and the mask used to train the models are compared and kept
+	Metric = metric.MahalanobisDistance
+	Mask = images[0]
+	assert.Nil(t, Merge(&other, MergeKeepBoth))
+	trainData := GetTrainingData()
+	assert.Equal(t, 10, len(trainData.Labels))
+	assert.Equal(t, metric.ChiSquare, trainData.SelectedMetric)
+	assert.Nil(t, trainData.Metric)
+	assert.Nil(t, trainData.Mask)
+}
+
+func TestSaveShards(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+	}()
+	Metric = metric.ChiSquare
+
+	Init(Params{})
+	assert.NotNil(t, SaveShards([]io.Writer{&bytes.Buffer{}}))
+
+	images := trainSamples(t)
+	assert.NotNil(t, SaveShards(nil))
+
+	// There are less labels than shards
+	writers := []io.Writer{&bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}}
+	assert.NotNil(t, SaveShards(writers))
+	assert.Equal(t, 0, writers[0].(*bytes.Buffer).Len())
+
+	// The sorted labels are assigned in round-robin
+	_, err := ShardLabels(0)
+	assert.NotNil(t, err)
+	assigned, err := ShardLabels(2)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"grass": 0, "rocks": 1, "wood": 0}, assigned)
+	assigned, err = ShardLabels(3)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"grass": 0, "rocks": 1, "wood": 2}, assigned)
+
+	var expected [][]Prediction
+	for _, img := range images {
+		predictions, err := PredictTopK(img, 3)
+		assert.Nil(t, err)
+		expected = append(expected, predictions)
+	}
+
+	var shard0, shard1 bytes.Buffer
+	assert.Nil(t, SaveShards([]io.Writer{&shard0, &shard1}))
+	assigned, err = ShardLabels(2)
+	assert.Nil(t, err)
+
+	// Search each shard and merge the predictions
+	results := make([][][]Prediction, len(images))
+	for index, shard := range []*bytes.Buffer{&shard0, &shard1} {
+		assert.Nil(t, Load(shard))
+		for _, label := range GetTrainingData().Labels {
+			assert.Equal(t, index, assigned[label])
+		}
+		for imgIndex, img := range images {
+			predictions, err := PredictTopK(img, 3)
+			assert.Nil(t, err)
+			results[imgIndex] = append(results[imgIndex], predictions)
+		}
+	}
+	assert.Equal(t, []string{"rocks"}, GetLabels())
+
+	for index := range images {
+		merged := MergePredictions(3, results[index]...)
+		assert.Equal(t, 3, len(merged))
+		for rank := range merged {
+			assert.Equal(t, expected[index][rank].Label, merged[rank].Label)
+			assert.InDelta(t, expected[index][rank].Distance, merged[rank].Distance, 1e-9)
+		}
+	}
+
+	// The shards keep the mask of the model (and can be merged back)
+	width, height := lbp.GetImageSize(images[0])
+	Mask = mask.Ellipse(width, height)
+	defer func() { Mask = nil }()
+	labels := []string{"rocks", "grass", "wood", "wood", "rocks"}
+	assert.Nil(t, Train(images, labels))
+	var masked bytes.Buffer
+	assert.Nil(t, Save(&masked))
+	shard0.Reset()
+	shard1.Reset()
+	assert.Nil(t, SaveShards([]io.Writer{&shard0, &shard1}))
+
+	Mask = nil
+	assert.Nil(t, Load(bytes.NewReader(shard1.Bytes())))
+	assert.True(t, equalMasks(mask.Ellipse(width, height), Mask))
+	assert.True(t, equalMasks(Mask, GetTrainingData().Mask))
+	label, distance, err := Predict(images[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "rocks", label)
+	assert.InDelta(t, 0, distance, 1e-9)
+
+	assert.Nil(t, Load(&masked))
+	_, err = RemoveLabel("rocks")
+	assert.Nil(t, err)
+	assert.Nil(t, Merge(bytes.NewReader(shard1.Bytes()), MergeKeepBoth))
+	assert.Equal(t, []string{"grass", "wood", "wood", "rocks", "rocks"}, GetTrainingData().Labels)
+}
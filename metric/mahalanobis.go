@@ -0,0 +1,113 @@
+package metric
+
+import (
+	"errors"
+
+	"github.com/kelvins/lbph/math"
+)
+
+// Fitter interface is implemented by the metrics whose parameters are estimated
+// from the training histograms (e.g. the Mahalanobis distance). The Fit function
+// returns a new (fitted) metric with the same name.
+type Fitter interface {
+	Fit(histograms [][]float64) (Metric, error)
+}
+
+// Regularization is the fraction of the mean variance added to the variance of
+// each bin (diagonal of the covariance matrix) by the Mahalanobis metrics, so
+// bins that never change in the training histograms do not divide by zero.
+const Regularization = 0.01
+
+// MaxCovarianceSize is the maximum histogram size supported by the full
+// covariance matrix (FullMahalanobisDistance). Bigger histograms should use
+// the diagonal approximation (MahalanobisDistance).
+const MaxCovarianceSize = 1024
+
+// Mahalanobis struct implements the Mahalanobis distance. The covariance is
+// estimated from the training histograms by the Fit function, using only the
+// variances (diagonal approximation) or the full covariance matrix.
+type Mahalanobis struct {
+	Full              bool        `json:"full"`
+	Variances         []float64   `json:"variances,omitempty"`
+	InverseCovariance [][]float64 `json:"inverse_covariance,omitempty"`
+}
+
+// Name function returns the metric name.
+func (m Mahalanobis) Name() string {
+	if m.Full {
+		return string(FullMahalanobisDistance)
+	}
+	return string(MahalanobisDistance)
+}
+
+// Distance function calculates the Mahalanobis distance between two histograms.
+// It returns an error if the metric was not fitted.
+func (m Mahalanobis) Distance(hist1, hist2 []float64) (float64, error) {
+	if m.Full {
+		if m.InverseCovariance == nil {
+			return 0, errors.New("The Mahalanobis metric was not trained. Select it before calling the Train function")
+		}
+		return math.MahalanobisDistance(hist1, hist2, m.InverseCovariance)
+	}
+
+	if m.Variances == nil {
+		return 0, errors.New("The Mahalanobis metric was not trained. Select it before calling the Train function")
+	}
+	return math.DiagonalMahalanobisDistance(hist1, hist2, m.Variances)
+}
+
+// Fit function estimates the (regularized) covariance from the histograms.
+func (m Mahalanobis) Fit(histograms [][]float64) (Metric, error) {
+	variances, err := math.Variances(histograms)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate the regularization based on the mean variance
+	var meanVariance float64
+	for _, variance := range variances {
+		meanVariance += variance
+	}
+	meanVariance /= float64(len(variances))
+	regularization := Regularization * meanVariance
+	if regularization == 0 {
+		// All histograms are equal, so it works as the euclidean distance
+		regularization = 1
+	}
+
+	if !m.Full {
+		for index := 0; index < len(variances); index++ {
+			variances[index] += regularization
+		}
+		return Mahalanobis{Variances: variances}, nil
+	}
+
+	if len(variances) > MaxCovarianceSize {
+		return nil, errors.New("The histograms are too big to calculate the full covariance matrix")
+	}
+
+	covariance, err := math.Covariance(histograms)
+	if err != nil {
+		return nil, err
+	}
+	for index := 0; index < len(covariance); index++ {
+		covariance[index][index] += regularization
+	}
+
+	inverseCovariance, err := math.Inverse(covariance)
+	if err != nil {
+		return nil, err
+	}
+	return Mahalanobis{Full: true, InverseCovariance: inverseCovariance}, nil
+}
+
+// Resolve function returns the metric registered with the name if the metric
+// passed by parameter is a Name, otherwise it returns the metric itself.
+func Resolve(m Metric) Metric {
+	if name, ok := m.(Name); ok {
+		if registered, err := Lookup(string(name)); err == nil {
+			return registered
+		}
+	}
+	return m
+}
@@ -0,0 +1,58 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMahalanobis(t *testing.T) {
+	histograms := [][]float64{{1, 2, 3}, {3, 2, 1}, {2, 2, 2}}
+
+	// Not trained
+	_, err := MahalanobisDistance.Distance([]float64{1, 2, 3}, []float64{1, 2, 3})
+	assert.NotNil(t, err)
+	_, err = FullMahalanobisDistance.Distance([]float64{1, 2, 3}, []float64{1, 2, 3})
+	assert.NotNil(t, err)
+
+	fitter, ok := Resolve(MahalanobisDistance).(Fitter)
+	assert.True(t, ok)
+
+	fitted, err := fitter.Fit(histograms)
+	assert.Nil(t, err)
+	assert.Equal(t, string(MahalanobisDistance), fitted.Name())
+
+	distance, err := fitted.Distance([]float64{1, 2, 3}, []float64{1, 2, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, distance)
+
+	// The second bin never changes, so it has a high weight
+	distance1, err := fitted.Distance([]float64{1, 2, 3}, []float64{2, 2, 3})
+	assert.Nil(t, err)
+	distance2, err := fitted.Distance([]float64{1, 2, 3}, []float64{1, 3, 3})
+	assert.Nil(t, err)
+	assert.True(t, distance2 > distance1)
+
+	fitter, ok = Resolve(FullMahalanobisDistance).(Fitter)
+	assert.True(t, ok)
+
+	fitted, err = fitter.Fit(histograms)
+	assert.Nil(t, err)
+	assert.Equal(t, string(FullMahalanobisDistance), fitted.Name())
+
+	// The first and third bins are negatively correlated
+	distance1, err = fitted.Distance([]float64{1, 2, 3}, []float64{2, 2, 2})
+	assert.Nil(t, err)
+	distance2, err = fitted.Distance([]float64{1, 2, 3}, []float64{2, 2, 4})
+	assert.Nil(t, err)
+	assert.True(t, distance2 > distance1)
+
+	_, err = fitter.Fit(nil)
+	assert.NotNil(t, err)
+
+	_, err = fitter.Fit([][]float64{make([]float64, MaxCovarianceSize+1)})
+	assert.NotNil(t, err)
+
+	// A metric that is not registered is not resolved
+	assert.Equal(t, Name("InvalidMetric"), Resolve(Name("InvalidMetric")))
+}
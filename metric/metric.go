@@ -1,9 +1,181 @@
+// metric package provides the metrics used to compare the histograms.
+// Custom metrics can be added by implementing the Metric interface and
+// registering it using the Register function.
 package metric
 
-// Metrics based on the math package
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/kelvins/lbph/math"
+)
+
+// Metric interface is implemented by every metric used to compare the histograms.
+// The name is used to identify the metric (e.g. when the model is serialized).
+type Metric interface {
+	Name() string
+	Distance(hist1, hist2 []float64) (float64, error)
+}
+
+// Name type identifies a metric registered in the metric package.
+// It implements the Metric interface by looking up the registered metric.
+type Name string
+
+// Metrics based on the math package.
+// ChiSquare uses the symmetric chi square statistic and
+// AsymmetricChiSquare uses the original one (divided by hist1).
+// Intersection and Correlation are converted to distances
+// (the lower the value, the more similar the histograms are).
+// The Mahalanobis metrics are estimated in the training step.
 const (
-	ChiSquare                   string = "ChiSquare"
-	EuclideanDistance           string = "EuclideanDistance"
-	NormalizedEuclideanDistance string = "NormalizedEuclideanDistance"
-	AbsoluteValue               string = "AbsoluteValue"
+	ChiSquare                   Name = "ChiSquare"
+	AsymmetricChiSquare         Name = "AsymmetricChiSquare"
+	EuclideanDistance           Name = "EuclideanDistance"
+	NormalizedEuclideanDistance Name = "NormalizedEuclideanDistance"
+	AbsoluteValue               Name = "AbsoluteValue"
+	Intersection                Name = "Intersection"
+	Correlation                 Name = "Correlation"
+	BhattacharyyaDistance       Name = "BhattacharyyaDistance"
+	HellingerDistance           Name = "HellingerDistance"
+	KullbackLeiblerDivergence   Name = "KullbackLeiblerDivergence"
+	JensenShannonDivergence     Name = "JensenShannonDivergence"
+	LogLikelihood               Name = "LogLikelihood"
+	EarthMoversDistance         Name = "EarthMoversDistance"
+	CyclicEarthMoversDistance   Name = "CyclicEarthMoversDistance"
+	QuadraticFormDistance       Name = "QuadraticFormDistance"
+	CosineDistance              Name = "CosineDistance"
+	MahalanobisDistance         Name = "MahalanobisDistance"
+	FullMahalanobisDistance     Name = "FullMahalanobisDistance"
 )
+
+// Bins is the number of bins of each region histogram (LBP codes with 8 neighbors).
+// It is used by the cross-bin metrics (Earth Mover's and quadratic-form distances).
+const Bins = 256
+
+// CodeSimilarity is the similarity between two LBP codes that differ by one bit,
+// used by the QuadraticFormDistance metric (similarity^hamming(code1, code2)).
+const CodeSimilarity = 0.5
+
+// Name function returns the metric name.
+func (name Name) Name() string {
+	return string(name)
+}
+
+// Distance function calculates the distance between two histograms using
+// the metric registered with this name.
+func (name Name) Distance(hist1, hist2 []float64) (float64, error) {
+	selectedMetric, err := Lookup(string(name))
+	if err != nil {
+		return 0, err
+	}
+	return selectedMetric.Distance(hist1, hist2)
+}
+
+// funcMetric struct is used to create a Metric from a function.
+type funcMetric struct {
+	name     string
+	distance func(hist1, hist2 []float64) (float64, error)
+}
+
+// Name function returns the metric name.
+func (m funcMetric) Name() string {
+	return m.name
+}
+
+// Distance function calculates the distance between two histograms.
+func (m funcMetric) Distance(hist1, hist2 []float64) (float64, error) {
+	return m.distance(hist1, hist2)
+}
+
+// New function creates a Metric based on a name and a distance function.
+func New(name string, distance func(hist1, hist2 []float64) (float64, error)) Metric {
+	return funcMetric{name: name, distance: distance}
+}
+
+// registry stores the registered metrics by name.
+var registry = map[string]Metric{}
+
+// registryMutex protects the registry from concurrent access.
+var registryMutex sync.RWMutex
+
+// init registers the metrics based on the math package.
+func init() {
+	mustRegister(New(string(ChiSquare), math.SymmetricChiSquare))
+	mustRegister(New(string(AsymmetricChiSquare), math.ChiSquare))
+	mustRegister(New(string(EuclideanDistance), math.EuclideanDistance))
+	mustRegister(New(string(NormalizedEuclideanDistance), math.NormalizedEuclideanDistance))
+	mustRegister(New(string(AbsoluteValue), math.AbsoluteValue))
+	mustRegister(New(string(Intersection), math.IntersectionDistance))
+	mustRegister(New(string(Correlation), math.CorrelationDistance))
+	mustRegister(New(string(BhattacharyyaDistance), math.BhattacharyyaDistance))
+	mustRegister(New(string(HellingerDistance), math.HellingerDistance))
+	mustRegister(New(string(KullbackLeiblerDivergence), math.KullbackLeiblerDivergence))
+	mustRegister(New(string(JensenShannonDivergence), math.JensenShannonDivergence))
+	mustRegister(New(string(LogLikelihood), math.LogLikelihood))
+	mustRegister(New(string(EarthMoversDistance), func(hist1, hist2 []float64) (float64, error) {
+		return math.EarthMoversDistance(hist1, hist2, Bins)
+	}))
+	mustRegister(New(string(CyclicEarthMoversDistance), func(hist1, hist2 []float64) (float64, error) {
+		return math.CyclicEarthMoversDistance(hist1, hist2, Bins)
+	}))
+	mustRegister(New(string(QuadraticFormDistance), func(hist1, hist2 []float64) (float64, error) {
+		return math.QuadraticFormDistance(hist1, hist2, Bins, CodeSimilarity)
+	}))
+	mustRegister(New(string(CosineDistance), math.CosineDistance))
+	mustRegister(Mahalanobis{})
+	mustRegister(Mahalanobis{Full: true})
+}
+
+// mustRegister function registers a metric and panics if an error occurs.
+// It is used only to register the built-in metrics.
+func mustRegister(m Metric) {
+	if err := Register(m); err != nil {
+		panic(err)
+	}
+}
+
+// Register function adds a custom metric to the registry so it can be selected
+// by its name (e.g. metric.Name("MyMetric")). The name must be unique.
+func Register(m Metric) error {
+	if m == nil {
+		return errors.New("The metric passed to the Register function is nil")
+	}
+	if m.Name() == "" {
+		return errors.New("The metric passed to the Register function has an empty name")
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, ok := registry[m.Name()]; ok {
+		return errors.New("A metric with the same name is already registered")
+	}
+	registry[m.Name()] = m
+	return nil
+}
+
+// Lookup function returns the metric registered with the name passed by parameter.
+func Lookup(name string) (Metric, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	m, ok := registry[name]
+	if !ok {
+		return nil, errors.New("Invalid metric selected to compare the histograms")
+	}
+	return m, nil
+}
+
+// Names function returns the names of all registered metrics (sorted).
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,52 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestName(t *testing.T) {
+	hist1 := []float64{1, 2, 3}
+	hist2 := []float64{1, 2, 5}
+
+	distance, err := EuclideanDistance.Distance(hist1, hist2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, distance)
+	assert.Equal(t, "EuclideanDistance", EuclideanDistance.Name())
+
+	_, err = Name("InvalidMetric").Distance(hist1, hist2)
+	assert.NotNil(t, err)
+}
+
+func TestRegister(t *testing.T) {
+	err := Register(nil)
+	assert.NotNil(t, err)
+
+	err = Register(New("", nil))
+	assert.NotNil(t, err)
+
+	// The built-in metrics are already registered
+	err = Register(New(string(ChiSquare), nil))
+	assert.NotNil(t, err)
+
+	custom := New("TestCustomMetric", func(hist1, hist2 []float64) (float64, error) {
+		return float64(len(hist1) + len(hist2)), nil
+	})
+	err = Register(custom)
+	assert.Nil(t, err)
+
+	selectedMetric, err := Lookup("TestCustomMetric")
+	assert.Nil(t, err)
+	assert.Equal(t, "TestCustomMetric", selectedMetric.Name())
+
+	distance, err := Name("TestCustomMetric").Distance([]float64{1}, []float64{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3.0, distance)
+
+	_, err = Lookup("InvalidMetric")
+	assert.NotNil(t, err)
+
+	assert.Contains(t, Names(), "TestCustomMetric")
+	assert.Contains(t, Names(), string(AbsoluteValue))
+}
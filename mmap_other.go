@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package lbph
+
+import (
+	"io/ioutil"
+)
+
+// mapFile function reads the file, as the memory mapping is not supported.
+func mapFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+// unmapFile function does nothing, as the memory mapping is not supported.
+func unmapFile(data []byte) error {
+	return nil
+}
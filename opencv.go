@@ -0,0 +1,346 @@
+package lbph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kelvins/lbph/histogram"
+	"github.com/kelvins/lbph/metric"
+)
+
+// OpenCVFormat type defines the formats of the OpenCV FileStorage.
+type OpenCVFormat string
+
+// Formats supported by the SaveOpenCV function.
+const (
+	OpenCVYAML OpenCVFormat = "YAML"
+	OpenCVXML  OpenCVFormat = "XML"
+)
+
+// opencvName is the name of the LBPHFaceRecognizer node in the FileStorage.
+const opencvName = "opencv_lbphfaces"
+
+// opencvStorage struct is the root of the OpenCV FileStorage.
+type opencvStorage struct {
+	XMLName xml.Name    `xml:"opencv_storage"`
+	Model   opencvModel `xml:"opencv_lbphfaces"`
+}
+
+// opencvModel struct stores the data of the OpenCV LBPHFaceRecognizer.
+type opencvModel struct {
+	Threshold  float64           `xml:"threshold"`
+	Radius     int               `xml:"radius"`
+	Neighbors  int               `xml:"neighbors"`
+	GridX      int               `xml:"grid_x"`
+	GridY      int               `xml:"grid_y"`
+	Histograms []opencvMatrix    `xml:"histograms>_"`
+	Labels     opencvMatrix      `xml:"labels"`
+	LabelsInfo []opencvLabelInfo `xml:"labelsInfo>_"`
+}
+
+// opencvMatrix struct stores an OpenCV matrix (opencv-matrix).
+type opencvMatrix struct {
+	TypeID string     `xml:"type_id,attr"`
+	Rows   int        `xml:"rows"`
+	Cols   int        `xml:"cols"`
+	Dt     string     `xml:"dt"`
+	Data   opencvData `xml:"data"`
+}
+
+// opencvLabelInfo struct stores the name (value) of an integer label.
+type opencvLabelInfo struct {
+	Label int    `xml:"label"`
+	Value string `xml:"value"`
+}
+
+// opencvData type stores the data of a matrix. In the XML format the values
+// are separated by spaces.
+type opencvData []float64
+
+// UnmarshalText function parses the values of the XML format.
+func (data *opencvData) UnmarshalText(text []byte) error {
+	var values opencvData
+	for _, field := range strings.Fields(string(text)) {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+	*data = values
+	return nil
+}
+
+// MarshalText function writes the values using the XML format.
+func (data opencvData) MarshalText() ([]byte, error) {
+	return []byte(data.join(" ")), nil
+}
+
+// join function formats the values separated by sep.
+func (data opencvData) join(sep string) string {
+	fields := make([]string, len(data))
+	for index, value := range data {
+		fields[index] = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return strings.Join(fields, sep)
+}
+
+// opencvCodes maps the LBP codes of OpenCV to the LBP codes of this package.
+// OpenCV sets the bit n when the neighbor at the angle 2*pi*n/8 (starting at
+// the right neighbor, counterclockwise) is equal or higher than the center.
+// This package concatenates the neighbors column by column (top left first),
+// so the first neighbor is the most significant bit.
+var opencvCodes = func() [256]int {
+	// Bit of this package for each OpenCV neighbor (right, top right, top,
+	// top left, left, bottom left, bottom and bottom right).
+	bits := [8]uint{1, 2, 4, 7, 6, 5, 3, 0}
+
+	var codes [256]int
+	for code := 0; code < 256; code++ {
+		for n, bit := range bits {
+			if code&(1<<uint(n)) != 0 {
+				codes[code] |= 1 << bit
+			}
+		}
+	}
+	return codes
+}()
+
+// fromOpenCV function converts an OpenCV histogram to the histogram of this package.
+// OpenCV stores the cells row by row and this package stores them column by column.
+func fromOpenCV(hist []float64, gridX, gridY int) []float64 {
+	converted := make([]float64, len(hist))
+	for row := 0; row < gridY; row++ {
+		for col := 0; col < gridX; col++ {
+			from := (row*gridX + col) * 256
+			to := (col*gridY + row) * 256
+			for code := 0; code < 256; code++ {
+				converted[to+opencvCodes[code]] = hist[from+code]
+			}
+		}
+	}
+	return converted
+}
+
+// toOpenCV function converts a histogram of this package to an OpenCV histogram.
+func toOpenCV(hist []float64, gridX, gridY int) []float64 {
+	converted := make([]float64, len(hist))
+	for row := 0; row < gridY; row++ {
+		for col := 0; col < gridX; col++ {
+			from := (col*gridY + row) * 256
+			to := (row*gridX + col) * 256
+			for code := 0; code < 256; code++ {
+				// OpenCV stores the histograms as float32
+				converted[to+code] = float64(float32(hist[from+opencvCodes[code]]))
+			}
+		}
+	}
+	return converted
+}
+
+// LoadOpenCV function reads a model saved by the OpenCV LBPHFaceRecognizer (FileStorage
+// YAML or XML) and replaces the training data, the LBPH parameters, the Metric and the
+// Mask (same as Load). The histograms are converted to the LBP codes and regions order
+// of this package and the Normalize parameter is enabled (OpenCV normalizes each region),
+// so they are comparable to the histograms calculated by Predict. The Metric is set to
+// ChiSquare and the OpenCV threshold (if it is defined and finite) is converted to the
+// Threshold variable, otherwise the Threshold is not changed.
+// Only the radius 1 and 8 neighbors are supported. Note that OpenCV interpolates the
+// diagonal neighbors, so the histograms are similar but not equal.
+func LoadOpenCV(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var storage opencvStorage
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		err = xml.Unmarshal(data, &storage)
+	} else {
+		err = unmarshalOpenCVYAML(data, &storage.Model)
+	}
+	if err != nil {
+		return err
+	}
+
+	cv := storage.Model
+	if cv.Radius != 1 || cv.Neighbors != 8 {
+		return errors.New("Only the OpenCV models with radius 1 and 8 neighbors are supported")
+	}
+	if cv.GridX <= 0 || cv.GridY <= 0 || cv.GridX > math.MaxUint8 || cv.GridY > math.MaxUint8 {
+		return errors.New("The OpenCV model has an invalid grid")
+	}
+	if len(cv.Histograms) == 0 || len(cv.Histograms) != len(cv.Labels.Data) {
+		return errors.New("The OpenCV model labels and histograms have different sizes")
+	}
+
+	// Label names (labelsInfo) or the integer labels
+	names := make(map[int]string)
+	for _, info := range cv.LabelsInfo {
+		names[info.Label] = info.Value
+	}
+
+	m := &model{
+		Version: modelVersion,
+		Params: Params{
+			Radius:    uint8(cv.Radius),
+			Neighbors: uint8(cv.Neighbors),
+			GridX:     uint8(cv.GridX),
+			GridY:     uint8(cv.GridY),
+			Normalize: true,
+		},
+		Metric: string(metric.ChiSquare),
+	}
+
+	for index, hist := range cv.Histograms {
+		if len(hist.Data) != cv.GridX*cv.GridY*256 {
+			return errors.New("The OpenCV model histograms have an invalid size")
+		}
+		m.Histograms = append(m.Histograms, fromOpenCV(hist.Data, cv.GridX, cv.GridY))
+
+		label := int(cv.Labels.Data[index])
+		if name, ok := names[label]; ok && name != "" {
+			m.Labels = append(m.Labels, name)
+		} else {
+			m.Labels = append(m.Labels, strconv.Itoa(label))
+		}
+	}
+
+	if err := m.apply(); err != nil {
+		return err
+	}
+
+	// OpenCV uses the alternative chi square (2x the symmetric chi square).
+	// The models without a threshold (DBL_MAX by default) keep the current one.
+	if cv.Threshold > 0 && cv.Threshold < math.MaxFloat64 {
+		Threshold = cv.Threshold / 2
+	}
+
+	return nil
+}
+
+// SaveOpenCV function writes the trained model using the format of the OpenCV
+// LBPHFaceRecognizer (FileStorage YAML or XML). The histograms are converted to the
+// LBP codes and regions order of OpenCV and each region is normalized. The labels are
+// stored as integers (if all the labels are integers) or as sequential integers with
+// the label names (labelsInfo). Only the uniform grid (without Regions), radius 1 and
+// 8 neighbors are supported. The Threshold is only exported for the ChiSquare metric.
+func SaveOpenCV(w io.Writer, format OpenCVFormat) error {
+	m, err := newModel(trainingData)
+	if err != nil {
+		return err
+	}
+	m.decodeHistograms()
+	if len(m.Params.Regions) > 0 {
+		return errors.New("The OpenCV models only support the uniform grid (without Regions)")
+	}
+	if m.Params.Radius != 1 || m.Params.Neighbors != 8 {
+		return errors.New("Only the radius 1 and 8 neighbors are supported by the OpenCV models")
+	}
+
+	gridX, gridY := int(m.Params.GridX), int(m.Params.GridY)
+	cv := opencvModel{
+		Threshold: math.MaxFloat64,
+		Radius:    int(m.Params.Radius),
+		Neighbors: int(m.Params.Neighbors),
+		GridX:     gridX,
+		GridY:     gridY,
+		Labels:    opencvMatrix{TypeID: "opencv-matrix", Rows: len(m.Labels), Cols: 1, Dt: "i"},
+	}
+	if m.Metric == string(metric.ChiSquare) && m.Params.Normalize && !math.IsInf(Threshold, 1) {
+		cv.Threshold = Threshold * 2
+	}
+
+	for _, hist := range m.Histograms {
+		if len(hist) != gridX*gridY*256 {
+			return errors.New("The histograms have an invalid size for the OpenCV model")
+		}
+		if !m.Params.Normalize {
+			hist = histogram.Normalize(hist)
+		}
+		cv.Histograms = append(cv.Histograms, opencvMatrix{
+			TypeID: "opencv-matrix", Rows: 1, Cols: len(hist), Dt: "f", Data: toOpenCV(hist, gridX, gridY),
+		})
+	}
+
+	// Integer labels
+	ids := make(map[string]int)
+	integers := true
+	for _, label := range m.Labels {
+		id, err := strconv.Atoi(label)
+		if err != nil || strconv.Itoa(id) != label || id < math.MinInt32 || id > math.MaxInt32 {
+			integers = false
+			break
+		}
+		ids[label] = id
+	}
+	if !integers {
+		ids = make(map[string]int)
+		for _, label := range m.Labels {
+			if _, ok := ids[label]; !ok {
+				ids[label] = len(ids)
+				cv.LabelsInfo = append(cv.LabelsInfo, opencvLabelInfo{Label: ids[label], Value: label})
+			}
+		}
+	}
+	for _, label := range m.Labels {
+		cv.Labels.Data = append(cv.Labels.Data, float64(ids[label]))
+	}
+
+	switch format {
+	case OpenCVYAML:
+		return writeOpenCVYAML(w, cv)
+	case OpenCVXML:
+		data, err := xml.MarshalIndent(opencvStorage{Model: cv}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "<?xml version=\"1.0\"?>\n%s\n", data)
+		return err
+	default:
+		return errors.New("Invalid OpenCV format")
+	}
+}
+
+// writeOpenCVYAML function writes the model using the YAML format of the OpenCV FileStorage.
+func writeOpenCVYAML(w io.Writer, cv opencvModel) error {
+	var buf bytes.Buffer
+	buf.WriteString("%YAML:1.0\n---\n" + opencvName + ":\n")
+	fmt.Fprintf(&buf, "   threshold: %s\n", strconv.FormatFloat(cv.Threshold, 'g', -1, 64))
+	fmt.Fprintf(&buf, "   radius: %d\n   neighbors: %d\n", cv.Radius, cv.Neighbors)
+	fmt.Fprintf(&buf, "   grid_x: %d\n   grid_y: %d\n", cv.GridX, cv.GridY)
+
+	buf.WriteString("   histograms:\n")
+	for _, hist := range cv.Histograms {
+		buf.WriteString("      - !!opencv-matrix\n")
+		writeOpenCVYAMLMatrix(&buf, hist, "         ")
+	}
+
+	buf.WriteString("   labels: !!opencv-matrix\n")
+	writeOpenCVYAMLMatrix(&buf, cv.Labels, "      ")
+
+	if len(cv.LabelsInfo) == 0 {
+		buf.WriteString("   labelsInfo:\n      []\n")
+	} else {
+		buf.WriteString("   labelsInfo:\n")
+		for _, info := range cv.LabelsInfo {
+			fmt.Fprintf(&buf, "      -\n         label: %d\n         value: %s\n", info.Label, strconv.Quote(info.Value))
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeOpenCVYAMLMatrix function writes an OpenCV matrix using the YAML format.
+func writeOpenCVYAMLMatrix(buf *bytes.Buffer, matrix opencvMatrix, indent string) {
+	fmt.Fprintf(buf, "%srows: %d\n%scols: %d\n%sdt: %s\n", indent, matrix.Rows, indent, matrix.Cols, indent, matrix.Dt)
+	fmt.Fprintf(buf, "%sdata: [ %s ]\n", indent, matrix.Data.join(", "))
+}
@@ -0,0 +1,174 @@
+package lbph
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kelvins/lbph/histogram"
+	"github.com/kelvins/lbph/lbp"
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenCVCodes(t *testing.T) {
+	// Position (x, y) of each OpenCV neighbor in a 3x3 image
+	neighbors := [][2]int{{2, 1}, {2, 0}, {1, 0}, {0, 0}, {0, 1}, {0, 2}, {1, 2}, {2, 2}}
+
+	for n, position := range neighbors {
+		img := image.NewGray(image.Rect(0, 0, 3, 3))
+		img.SetGray(1, 1, color.Gray{Y: 100})
+		img.SetGray(position[0], position[1], color.Gray{Y: 200})
+
+		pixels, err := lbp.Calculate(img, 1, 8)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(opencvCodes[1<<uint(n)]), pixels[0][0])
+	}
+
+	// The histograms are converted back and forth
+	hist := make([]float64, 6*256)
+	for index := range hist {
+		hist[index] = float64(index)
+	}
+	assert.Equal(t, hist, fromOpenCV(toOpenCV(hist, 2, 3), 2, 3))
+	assert.NotEqual(t, hist, toOpenCV(hist, 2, 3))
+}
+
+func TestLoadOpenCV(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+		Threshold = math.Inf(1)
+	}()
+
+	expected := [][]float64{make([]float64, 256), make([]float64, 256)}
+	expected[0][opencvCodes[1]] = 0.25
+	expected[0][255] = 0.75
+	expected[1][0] = 1
+
+	var tTable = []struct {
+		path      string
+		threshold float64
+	}{
+		{"./testdata/opencv_lbphfaces.yml", 1},
+		{"./testdata/opencv_lbphfaces.xml", 5},
+	}
+
+	for _, pair := range tTable {
+		// The models without a threshold keep the current one
+		Threshold = 5
+		file, err := os.Open(pair.path)
+		assert.Nil(t, err)
+		err = LoadOpenCV(file)
+		file.Close()
+		assert.Nil(t, err, pair.path)
+
+		trainData := GetTrainingData()
+		assert.Equal(t, []string{"alice", "3"}, trainData.Labels)
+		assert.Equal(t, expected, trainData.Histograms)
+		assert.Equal(t, Params{Radius: 1, Neighbors: 8, GridX: 1, GridY: 1, Normalize: true}, trainData.Params)
+		assert.Equal(t, metric.ChiSquare, Metric)
+		assert.Equal(t, pair.threshold, Threshold)
+	}
+
+	// Invalid models
+	var invalid = []string{
+		"",
+		"<opencv_storage>",
+		"opencv_lbphfaces:\n   radius: 2\n   neighbors: 8\n   grid_x: 1\n   grid_y: 1\n",
+		"opencv_lbphfaces:\n   radius: 1\n   neighbors: 8\n   grid_x: 0\n   grid_y: 1\n",
+		"opencv_lbphfaces:\n   radius: 1\n   neighbors: 8\n   grid_x: 1\n   grid_y: 1\n   histograms:\n      - { rows: 1, cols: 2, dt: f, data: [ 0., 1. ] }\n   labels: { rows: 1, cols: 1, dt: i, data: [ 1 ] }\n",
+	}
+	for _, data := range invalid {
+		assert.NotNil(t, LoadOpenCV(strings.NewReader(data)), data)
+	}
+}
+
+func TestParseOpenCVYAML(t *testing.T) {
+	data := "%YAML:1.0\n---\nroot:\n   number: 2.\n   text: \"a: b\"\n   flow: { rows: 1, data: [ 1, 2,\n      3 ] }\n" +
+		"   items:\n      - { label: 0, value: 'it''s' }\n      - label: 1\n        value: bob\n      -\n         label: 2\n   empty:\n      []\n"
+
+	node, err := parseOpenCVYAML([]byte(data))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"root": map[string]interface{}{
+			"number": "2.",
+			"text":   "a: b",
+			"flow":   map[string]interface{}{"rows": "1", "data": []interface{}{"1", "2", "3"}},
+			"items": []interface{}{
+				map[string]interface{}{"label": "0", "value": "it's"},
+				map[string]interface{}{"label": "1", "value": "bob"},
+				map[string]interface{}{"label": "2"},
+			},
+			"empty": []interface{}{},
+		},
+	}, node)
+
+	value, err := yamlFloat(".Inf")
+	assert.Nil(t, err)
+	assert.True(t, math.IsInf(value, 1))
+
+	// Invalid documents
+	for _, invalid := range []string{"", "root: [ 1, 2", "root:\n   - 1\n   key: 2\n", "[ 1 ]: 2\n"} {
+		_, err := parseOpenCVYAML([]byte(invalid))
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestSaveOpenCV(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+		Threshold = math.Inf(1)
+	}()
+
+	images := trainSamples(t)
+	expected := GetTrainingData()
+
+	var buf bytes.Buffer
+	assert.NotNil(t, SaveOpenCV(&buf, OpenCVFormat("Invalid")))
+
+	for _, format := range []OpenCVFormat{OpenCVYAML, OpenCVXML} {
+		buf.Reset()
+		assert.Nil(t, SaveOpenCV(&buf, format))
+		assert.Nil(t, LoadOpenCV(&buf))
+
+		// The histograms are normalized by the export
+		trainData := GetTrainingData()
+		assert.Equal(t, expected.Labels, trainData.Labels)
+		assert.True(t, trainData.Params.Normalize)
+		for index, hist := range trainData.Histograms {
+			assert.InDeltaSlice(t, histogram.Normalize(expected.Histograms[index]), hist, 1e-6)
+		}
+
+		label, _, err := Predict(images[0])
+		assert.Nil(t, err)
+		assert.Equal(t, "rocks", label)
+	}
+
+	// Normalized model with integer labels and threshold
+	Init(Params{Normalize: true})
+	Metric = metric.ChiSquare
+	Threshold = 1.5
+	err := Train(images[:3], []string{"1", "2", "3"})
+	assert.Nil(t, err)
+
+	buf.Reset()
+	assert.Nil(t, SaveOpenCV(&buf, OpenCVYAML))
+	assert.Contains(t, buf.String(), "threshold: 3\n")
+	assert.Contains(t, buf.String(), "labelsInfo:\n      []\n")
+	assert.Nil(t, LoadOpenCV(&buf))
+	assert.Equal(t, []string{"1", "2", "3"}, GetTrainingData().Labels)
+	assert.Equal(t, 1.5, Threshold)
+
+	// The regions are not supported by OpenCV
+	Init(Params{Regions: []histogram.Region{{X: 0, Y: 0, Width: 1, Height: 1}}})
+	err = Train(images[:1], []string{"rocks"})
+	assert.Nil(t, err)
+	assert.NotNil(t, SaveOpenCV(&buf, OpenCVYAML))
+}
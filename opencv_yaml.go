@@ -0,0 +1,381 @@
+package lbph
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// errOpenCVYAML is returned when the YAML FileStorage cannot be parsed.
+var errOpenCVYAML = errors.New("The OpenCV model is not a valid YAML FileStorage")
+
+// yamlLine struct stores a line of the YAML FileStorage (without the indentation).
+type yamlLine struct {
+	indent int
+	text   string
+}
+
+// parseOpenCVYAML function parses the subset of YAML written by the OpenCV
+// FileStorage: block mappings and sequences, flow sequences and mappings (that
+// may span multiple lines), quoted strings and the !!opencv-matrix tags. The
+// mappings are returned as map[string]interface{}, the sequences as []interface{}
+// and the scalars as strings.
+func parseOpenCVYAML(data []byte) (interface{}, error) {
+	var lines []yamlLine
+	for _, line := range strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n") {
+		text := strings.TrimSpace(line)
+
+		// Continuation of a flow sequence or mapping
+		if len(lines) > 0 && !isBalanced(lines[len(lines)-1].text) {
+			lines[len(lines)-1].text += " " + text
+			continue
+		}
+
+		// Skip the directives (%YAML:1.0), the document markers and the comments
+		if text == "" || text == "---" || text == "..." || strings.HasPrefix(text, "%") || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		text = strings.TrimSpace(strings.Replace(text, "!!opencv-matrix", "", -1))
+		lines = append(lines, yamlLine{indent: len(line) - len(strings.TrimLeft(line, " ")), text: text})
+	}
+	if len(lines) == 0 || !isBalanced(lines[len(lines)-1].text) {
+		return nil, errOpenCVYAML
+	}
+	return parseYAMLNested(lines)
+}
+
+// isBalanced function checks if the flow sequences and mappings of the text are closed.
+func isBalanced(text string) bool {
+	depth := 0
+	quoted := false
+	for index := 0; index < len(text); index++ {
+		switch {
+		case text[index] == '"' && (index == 0 || text[index-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case text[index] == '[' || text[index] == '{':
+			depth++
+		case text[index] == ']' || text[index] == '}':
+			depth--
+		}
+	}
+	return depth <= 0 && !quoted
+}
+
+// childLines function returns the first lines with an indentation higher than indent.
+func childLines(lines []yamlLine, indent int) []yamlLine {
+	count := 0
+	for count < len(lines) && lines[count].indent > indent {
+		count++
+	}
+	return lines[:count]
+}
+
+// isYAMLItem function checks if the text is an item of a block sequence.
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isYAMLKey function checks if the text is an entry (key: value) of a block mapping.
+func isYAMLKey(text string) bool {
+	if text == "" || strings.ContainsAny(text[:1], "[{\"'") {
+		return false
+	}
+	return strings.Contains(text, ": ") || strings.HasSuffix(text, ":")
+}
+
+// splitYAMLKey function splits an entry of a mapping into its key and value.
+func splitYAMLKey(text string) (string, string) {
+	index := strings.Index(text, ": ")
+	if index < 0 {
+		return strings.TrimSuffix(text, ":"), ""
+	}
+	return strings.TrimSpace(text[:index]), strings.TrimSpace(text[index+1:])
+}
+
+// parseYAMLNested function parses the lines of a node: a block mapping, a block
+// sequence or a single value.
+func parseYAMLNested(lines []yamlLine) (interface{}, error) {
+	if len(lines) == 1 && !isYAMLItem(lines[0].text) && !isYAMLKey(lines[0].text) {
+		return parseYAMLValue(lines[0].text)
+	}
+
+	node, rest, err := parseYAMLBlock(lines)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errOpenCVYAML
+	}
+	return node, nil
+}
+
+// parseYAMLBlock function parses the block mapping or sequence starting at the
+// first line. It returns the node and the lines after the block.
+func parseYAMLBlock(lines []yamlLine) (interface{}, []yamlLine, error) {
+	indent := lines[0].indent
+
+	if isYAMLItem(lines[0].text) {
+		var sequence []interface{}
+		for len(lines) > 0 && lines[0].indent == indent && isYAMLItem(lines[0].text) {
+			item := strings.TrimSpace(strings.TrimPrefix(lines[0].text, "-"))
+			children := childLines(lines[1:], indent)
+			lines = lines[1+len(children):]
+
+			var node interface{}
+			var err error
+			switch {
+			case item == "" && len(children) == 0:
+				node = ""
+			case item == "":
+				node, err = parseYAMLNested(children)
+			case !isYAMLKey(item) && len(children) == 0:
+				node, err = parseYAMLValue(item)
+			default:
+				// Block mapping starting in the item line (e.g. "- label: 7")
+				first := yamlLine{indent: indent + 2, text: item}
+				if len(children) > 0 {
+					first.indent = children[0].indent
+				}
+				node, err = parseYAMLNested(append([]yamlLine{first}, children...))
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			sequence = append(sequence, node)
+		}
+		return sequence, lines, nil
+	}
+
+	mapping := make(map[string]interface{})
+	for len(lines) > 0 && lines[0].indent == indent {
+		if !isYAMLKey(lines[0].text) {
+			return nil, nil, errOpenCVYAML
+		}
+		key, value := splitYAMLKey(lines[0].text)
+		children := childLines(lines[1:], indent)
+		lines = lines[1+len(children):]
+
+		var node interface{}
+		var err error
+		switch {
+		case value != "" && len(children) > 0:
+			err = errOpenCVYAML
+		case value != "":
+			node, err = parseYAMLValue(value)
+		case len(children) > 0:
+			node, err = parseYAMLNested(children)
+		default:
+			node = ""
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		mapping[key] = node
+	}
+	return mapping, lines, nil
+}
+
+// splitYAMLFlow function splits the elements of a flow sequence or mapping
+// (without the brackets) separated by commas.
+func splitYAMLFlow(text string) []string {
+	var elements []string
+	depth, start := 0, 0
+	quoted := false
+	for index := 0; index < len(text); index++ {
+		switch {
+		case text[index] == '"' && (index == 0 || text[index-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case text[index] == '[' || text[index] == '{':
+			depth++
+		case text[index] == ']' || text[index] == '}':
+			depth--
+		case text[index] == ',' && depth == 0:
+			elements = append(elements, strings.TrimSpace(text[start:index]))
+			start = index + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		elements = append(elements, last)
+	}
+	return elements
+}
+
+// parseYAMLValue function parses a flow sequence, a flow mapping or a scalar.
+func parseYAMLValue(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, errOpenCVYAML
+		}
+		sequence := []interface{}{}
+		for _, element := range splitYAMLFlow(text[1 : len(text)-1]) {
+			node, err := parseYAMLValue(element)
+			if err != nil {
+				return nil, err
+			}
+			sequence = append(sequence, node)
+		}
+		return sequence, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, errOpenCVYAML
+		}
+		mapping := make(map[string]interface{})
+		for _, element := range splitYAMLFlow(text[1 : len(text)-1]) {
+			if !isYAMLKey(element) {
+				return nil, errOpenCVYAML
+			}
+			key, value := splitYAMLKey(element)
+			node, err := parseYAMLValue(value)
+			if err != nil {
+				return nil, err
+			}
+			mapping[key] = node
+		}
+		return mapping, nil
+	case strings.HasPrefix(text, "\""):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, errOpenCVYAML
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, errOpenCVYAML
+		}
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	}
+	return text, nil
+}
+
+// yamlFloat function converts a scalar to a float (including .Inf and .NaN).
+func yamlFloat(node interface{}) (float64, error) {
+	text, ok := node.(string)
+	if !ok {
+		return 0, errOpenCVYAML
+	}
+	switch strings.ToLower(text) {
+	case ".inf", "+.inf":
+		return math.Inf(1), nil
+	case "-.inf":
+		return math.Inf(-1), nil
+	case ".nan":
+		return math.NaN(), nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, errOpenCVYAML
+	}
+	return value, nil
+}
+
+// yamlInt function converts a scalar to an integer.
+func yamlInt(node interface{}) (int, error) {
+	text, ok := node.(string)
+	if !ok {
+		return 0, errOpenCVYAML
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, errOpenCVYAML
+	}
+	return value, nil
+}
+
+// yamlMatrix function converts a mapping to an OpenCV matrix.
+func yamlMatrix(node interface{}) (opencvMatrix, error) {
+	var matrix opencvMatrix
+	mapping, ok := node.(map[string]interface{})
+	if !ok {
+		return matrix, errOpenCVYAML
+	}
+
+	var err error
+	if matrix.Rows, err = yamlInt(mapping["rows"]); err != nil {
+		return matrix, err
+	}
+	if matrix.Cols, err = yamlInt(mapping["cols"]); err != nil {
+		return matrix, err
+	}
+	matrix.Dt, _ = mapping["dt"].(string)
+
+	data, ok := mapping["data"].([]interface{})
+	if !ok {
+		return matrix, errOpenCVYAML
+	}
+	for _, element := range data {
+		value, err := yamlFloat(element)
+		if err != nil {
+			return matrix, err
+		}
+		matrix.Data = append(matrix.Data, value)
+	}
+	return matrix, nil
+}
+
+// unmarshalOpenCVYAML function reads the LBPHFaceRecognizer node of the YAML FileStorage.
+// The missing fields keep the zero value (e.g. the threshold).
+func unmarshalOpenCVYAML(data []byte, cv *opencvModel) error {
+	root, err := parseOpenCVYAML(data)
+	if err != nil {
+		return err
+	}
+	rootMapping, ok := root.(map[string]interface{})
+	if !ok {
+		return errOpenCVYAML
+	}
+	node, ok := rootMapping[opencvName].(map[string]interface{})
+	if !ok {
+		return errors.New("The OpenCV model does not contain the " + opencvName + " node")
+	}
+
+	if value, ok := node["threshold"]; ok {
+		if cv.Threshold, err = yamlFloat(value); err != nil {
+			return err
+		}
+	}
+	for key, field := range map[string]*int{
+		"radius": &cv.Radius, "neighbors": &cv.Neighbors, "grid_x": &cv.GridX, "grid_y": &cv.GridY,
+	} {
+		if value, ok := node[key]; ok {
+			if *field, err = yamlInt(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	if histograms, ok := node["histograms"].([]interface{}); ok {
+		for _, hist := range histograms {
+			matrix, err := yamlMatrix(hist)
+			if err != nil {
+				return err
+			}
+			cv.Histograms = append(cv.Histograms, matrix)
+		}
+	}
+	if labels, ok := node["labels"]; ok {
+		if cv.Labels, err = yamlMatrix(labels); err != nil {
+			return err
+		}
+	}
+
+	if labelsInfo, ok := node["labelsInfo"].([]interface{}); ok {
+		for _, element := range labelsInfo {
+			mapping, ok := element.(map[string]interface{})
+			if !ok {
+				return errOpenCVYAML
+			}
+			var info opencvLabelInfo
+			if info.Label, err = yamlInt(mapping["label"]); err != nil {
+				return err
+			}
+			info.Value, _ = mapping["value"].(string)
+			cv.LabelsInfo = append(cv.LabelsInfo, info)
+		}
+	}
+	return nil
+}
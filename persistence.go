@@ -0,0 +1,753 @@
+package lbph
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/kelvins/lbph/calibration"
+	"github.com/kelvins/lbph/compact"
+	"github.com/kelvins/lbph/histogram"
+	"github.com/kelvins/lbph/metric"
+)
+
+// modelMagic identifies the binary model format.
+var modelMagic = []byte("LBPH")
+
+// modelVersion is the current version of the model formats (binary and JSON).
+// Version 2 added the Normalize and Storage parameters (and the compact histograms).
+const modelVersion uint16 = 2
+
+// Sections of the binary model format. Each section is stored as its tag,
+// its size and its data, so unknown sections can be skipped.
+const (
+	sectionEnd uint16 = iota
+	sectionParams
+	sectionMetric
+	sectionSamples
+	sectionFittedMetric
+	sectionCalibration
+	sectionPrototypes
+	sectionImages
+	sectionMask
+	sectionCompactHistograms
+	sectionSampleRecords
+	sectionPrototypeParams
+)
+
+// Calibration types stored in the models.
+const (
+	calibrationLogistic = "Logistic"
+	calibrationIsotonic = "Isotonic"
+)
+
+// SaveImages defines if the training images are stored by the Save function.
+// The images are not needed by the Predict step, so the default is false.
+var SaveImages = false
+
+// model struct is the representation of a trained model shared by the binary
+// and the JSON formats. The images and the mask are stored as PNG.
+type model struct {
+	Version             uint16              `json:"version"`
+	Params              Params              `json:"params"`
+	Width               int                 `json:"width"`
+	Height              int                 `json:"height"`
+	Metric              string              `json:"metric"`
+	Labels              []string            `json:"labels"`
+	Samples             []Sample            `json:"samples,omitempty"`
+	Histograms          [][]float64         `json:"histograms"`
+	CompactHistograms   []compact.Histogram `json:"-"`
+	FittedMetric        *metric.Mahalanobis `json:"fitted_metric,omitempty"`
+	Calibration         *modelCalibration   `json:"calibration,omitempty"`
+	PrototypeLabels     []string            `json:"prototype_labels,omitempty"`
+	PrototypeHistograms [][]float64         `json:"prototype_histograms,omitempty"`
+	PrototypeParams     *PrototypeParams    `json:"prototype_params,omitempty"`
+	Images              [][]byte            `json:"images,omitempty"`
+	Mask                []byte              `json:"mask,omitempty"`
+}
+
+// modelCalibration struct stores the parameters of the supported calibrations.
+type modelCalibration struct {
+	Type        string    `json:"type"`
+	A           float64   `json:"a,omitempty"`
+	B           float64   `json:"b,omitempty"`
+	Distances   []float64 `json:"distances,omitempty"`
+	Confidences []float64 `json:"confidences,omitempty"`
+}
+
+// encodeImage function encodes the image using the PNG format.
+func encodeImage(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeImage function decodes an image stored in the model.
+func decodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// newModel function creates the model based on the training data passed by
+// parameter, including the metric and the mask used to train it (not the current
+// Metric and Mask). The images are only stored if SaveImages is true.
+func newModel(data *TrainingData) (*model, error) {
+
+	// Check if we have data in the TrainingData struct.
+	if data == nil {
+		return nil, errors.New("The algorithm was not trained yet")
+	}
+	if data.SelectedMetric == nil {
+		return nil, errors.New("The metric is nil")
+	}
+
+	m := &model{
+		Version:             modelVersion,
+		Params:              data.Params,
+		Width:               data.Width,
+		Height:              data.Height,
+		Metric:              data.SelectedMetric.Name(),
+		Labels:              data.Labels,
+		Samples:             data.Samples,
+		Histograms:          data.Histograms,
+		CompactHistograms:   data.CompactHistograms,
+		PrototypeLabels:     data.PrototypeLabels,
+		PrototypeHistograms: data.PrototypeHistograms,
+	}
+
+	// Prototype matching mode (used when the training data changes)
+	if data.Prototypes.Mode != NoPrototype {
+		prototypes := data.Prototypes
+		m.PrototypeParams = &prototypes
+	}
+
+	// The histograms of the galleries are decoded
+	if data.gallery != nil {
+		var err error
+		if m.Histograms, err = data.getHistograms(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fitted metric (e.g. Mahalanobis)
+	if data.Metric != nil {
+		mahalanobis, ok := data.Metric.(metric.Mahalanobis)
+		if !ok {
+			return nil, errors.New("The fitted metric cannot be saved")
+		}
+		m.FittedMetric = &mahalanobis
+	}
+
+	// Calibration
+	switch calibrator := data.Calibration.(type) {
+	case nil:
+	case calibration.Logistic:
+		m.Calibration = &modelCalibration{Type: calibrationLogistic, A: calibrator.A, B: calibrator.B}
+	case calibration.Isotonic:
+		m.Calibration = &modelCalibration{
+			Type:        calibrationIsotonic,
+			Distances:   calibrator.Distances,
+			Confidences: calibrator.Confidences,
+		}
+	default:
+		return nil, errors.New("The calibration cannot be saved")
+	}
+
+	// Images (optional)
+	if SaveImages {
+		for _, img := range data.Images {
+			encoded, err := encodeImage(img)
+			if err != nil {
+				return nil, err
+			}
+			m.Images = append(m.Images, encoded)
+		}
+	}
+
+	// Mask
+	if data.Mask != nil {
+		encoded, err := encodeImage(data.Mask)
+		if err != nil {
+			return nil, err
+		}
+		m.Mask = encoded
+	}
+
+	return m, nil
+}
+
+// decode function checks the model and converts it to the training data
+// (including the mask), without changing the current state.
+func (m *model) decode() (*TrainingData, error) {
+	if m.Version == 0 || m.Version > modelVersion {
+		return nil, errors.New("The model version is not supported")
+	}
+	if err := m.checkHistograms(); err != nil {
+		return nil, err
+	}
+	if err := checkSamples(m.Samples, m.Labels); err != nil {
+		return nil, err
+	}
+	if len(m.PrototypeLabels) != len(m.PrototypeHistograms) {
+		return nil, errors.New("The model prototype labels and histograms have different sizes")
+	}
+	if len(m.Images) > 0 && len(m.Images) != len(m.Labels) {
+		return nil, errors.New("The model images and labels have different sizes")
+	}
+	if _, err := metric.Lookup(m.Metric); err != nil {
+		return nil, errors.New("The metric of the model is not registered")
+	}
+
+	loaded := &TrainingData{
+		Labels:              m.Labels,
+		Samples:             m.Samples,
+		Histograms:          m.Histograms,
+		CompactHistograms:   m.CompactHistograms,
+		Params:              m.Params,
+		Width:               m.Width,
+		Height:              m.Height,
+		SelectedMetric:      metric.Name(m.Metric),
+		PrototypeLabels:     m.PrototypeLabels,
+		PrototypeHistograms: m.PrototypeHistograms,
+	}
+
+	// The models saved without the prototype matching mode use the current one
+	if m.PrototypeParams != nil {
+		loaded.Prototypes = *m.PrototypeParams
+	} else if len(m.PrototypeLabels) > 0 {
+		loaded.Prototypes = Prototypes
+	}
+
+	if m.FittedMetric != nil {
+		loaded.Metric = *m.FittedMetric
+	}
+
+	// Convert the histograms to the compact storage (e.g. loaded from JSON).
+	if loaded.CompactHistograms == nil {
+		if err := loaded.compactHistograms(); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.Calibration != nil {
+		switch m.Calibration.Type {
+		case calibrationLogistic:
+			loaded.Calibration = calibration.Logistic{A: m.Calibration.A, B: m.Calibration.B}
+		case calibrationIsotonic:
+			if len(m.Calibration.Distances) != len(m.Calibration.Confidences) {
+				return nil, errors.New("The model calibration is invalid")
+			}
+			loaded.Calibration = calibration.Isotonic{
+				Distances:   m.Calibration.Distances,
+				Confidences: m.Calibration.Confidences,
+			}
+		default:
+			return nil, errors.New("The calibration of the model is not supported")
+		}
+	}
+
+	for _, data := range m.Images {
+		img, err := decodeImage(data)
+		if err != nil {
+			return nil, err
+		}
+		loaded.Images = append(loaded.Images, img)
+	}
+
+	if m.Mask != nil {
+		var err error
+		if loaded.Mask, err = decodeImage(m.Mask); err != nil {
+			return nil, err
+		}
+	}
+
+	return loaded, nil
+}
+
+// apply function checks the model and replaces the current state (training
+// data, LBPH parameters, Metric and Mask). The state is not changed if the
+// model is invalid.
+func (m *model) apply() error {
+	loaded, err := m.decode()
+	if err != nil {
+		return err
+	}
+
+	// Replace the current state
+	lbphParams = loaded.Params
+	Metric = metric.Name(m.Metric)
+	Mask = loaded.Mask
+	trainingData = loaded
+
+	return nil
+}
+
+// checkHistograms function checks if the model has one histogram per label and
+// if all histograms (or compact histograms) have the same size.
+func (m *model) checkHistograms() error {
+	sizes := make([]int, 0, len(m.Labels))
+	if m.CompactHistograms != nil {
+		for _, hist := range m.CompactHistograms {
+			if hist.Storage() != m.Params.Storage {
+				return errors.New("The model histograms have a different storage")
+			}
+			sizes = append(sizes, hist.Len())
+		}
+	} else {
+		for _, hist := range m.Histograms {
+			sizes = append(sizes, len(hist))
+		}
+	}
+
+	if len(m.Labels) == 0 || len(m.Labels) != len(sizes) {
+		return errors.New("The model labels and histograms have different sizes")
+	}
+	for _, size := range sizes {
+		if size == 0 || size != sizes[0] {
+			return errors.New("The model histograms have different sizes")
+		}
+	}
+	return nil
+}
+
+// checkSamples function checks if the samples (optional) match the labels.
+func checkSamples(samples []Sample, labels []string) error {
+	if samples == nil {
+		return nil
+	}
+	if len(samples) != len(labels) {
+		return errors.New("The model samples and labels have different sizes")
+	}
+	for index, sample := range samples {
+		if sample.Label != labels[index] {
+			return errors.New("The model samples and labels are different")
+		}
+	}
+	return nil
+}
+
+// writeSamples function writes the samples (ID and metadata) to the encoder.
+// The labels are not written, they are the labels of the model.
+func (e *encoder) writeSamples(samples []Sample) {
+	e.writeUint32(uint32(len(samples)))
+	for _, sample := range samples {
+		e.writeUint64(uint64(sample.ID))
+
+		// Sort the keys, so the output is deterministic
+		keys := make([]string, 0, len(sample.Metadata))
+		for key := range sample.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		e.writeUint32(uint32(len(keys)))
+		for _, key := range keys {
+			e.writeString(key)
+			e.writeString(sample.Metadata[key])
+		}
+	}
+}
+
+// readSamples function reads the samples written by writeSamples using the labels.
+func (d *decoder) readSamples(labels []string) []Sample {
+	count := d.readCount(12)
+	if d.err == nil && count != len(labels) {
+		d.err = errors.New("The model samples and labels have different sizes")
+	}
+
+	var samples []Sample
+	for index := 0; index < count && d.err == nil; index++ {
+		sample := Sample{ID: int64(d.readUint64()), Label: labels[index]}
+		entries := d.readCount(8)
+		for entry := 0; entry < entries && d.err == nil; entry++ {
+			if sample.Metadata == nil {
+				sample.Metadata = make(map[string]string)
+			}
+			key := d.readString()
+			sample.Metadata[key] = d.readString()
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// decodeHistograms function decodes the compact histograms (if needed), so
+// the Histograms slice can be used by the formats that only support float64.
+func (m *model) decodeHistograms() {
+	if m.CompactHistograms == nil {
+		return
+	}
+	m.Histograms = make([][]float64, len(m.CompactHistograms))
+	for index, hist := range m.CompactHistograms {
+		m.Histograms[index] = hist.Float64s()
+	}
+	m.CompactHistograms = nil
+}
+
+// writeCompact function writes the compact histograms to the encoder.
+func (e *encoder) writeCompact(storage compact.Storage, histograms []compact.Histogram) {
+	e.writeString(string(storage))
+	e.writeUint32(uint32(len(histograms)))
+	for _, hist := range histograms {
+		switch converted := hist.(type) {
+		case compact.Float64Histogram:
+			e.writeFloat64s(converted)
+		case compact.Float32Histogram:
+			e.writeUint32(uint32(len(converted)))
+			for _, value := range converted {
+				e.writeFloat32(value)
+			}
+		case compact.Uint16Histogram:
+			e.writeFloat64(converted.Scale)
+			e.writeUint32(uint32(len(converted.Counts)))
+			for _, count := range converted.Counts {
+				e.writeUint16(count)
+			}
+		case compact.SparseHistogram:
+			e.writeUint32(uint32(converted.Size))
+			e.writeUint32(uint32(len(converted.Indexes)))
+			for index, position := range converted.Indexes {
+				e.writeUint32(position)
+				e.writeFloat32(converted.Values[index])
+			}
+		}
+	}
+}
+
+// readCompact function reads the compact histograms written by writeCompact.
+func (d *decoder) readCompact() []compact.Histogram {
+	storage := compact.Storage(d.readString())
+	count := d.readCount(4)
+
+	histograms := make([]compact.Histogram, 0, count)
+	for index := 0; index < count && d.err == nil; index++ {
+		switch storage {
+		case compact.Float64:
+			histograms = append(histograms, compact.Float64Histogram(d.readFloat64s()))
+		case compact.Float32:
+			hist := make(compact.Float32Histogram, d.readCount(4))
+			for bin := range hist {
+				hist[bin] = d.readFloat32()
+			}
+			histograms = append(histograms, hist)
+		case compact.Uint16:
+			hist := compact.Uint16Histogram{Scale: d.readFloat64()}
+			hist.Counts = make([]uint16, d.readCount(2))
+			for bin := range hist.Counts {
+				hist.Counts[bin] = d.readUint16()
+			}
+			histograms = append(histograms, hist)
+		case compact.Sparse:
+			hist := compact.SparseHistogram{Size: int(d.readUint32())}
+			values := d.readCount(8)
+			hist.Indexes = make([]uint32, values)
+			hist.Values = make([]float32, values)
+			for bin := 0; bin < values; bin++ {
+				hist.Indexes[bin] = d.readUint32()
+				hist.Values[bin] = d.readFloat32()
+				if int(hist.Indexes[bin]) >= hist.Size {
+					d.err = errCorrupted
+				}
+			}
+			histograms = append(histograms, hist)
+		default:
+			d.err = errors.New("The storage of the model histograms is not supported")
+		}
+	}
+	return histograms
+}
+
+// writeSection function writes a section (tag, size and data) to the encoder.
+func (e *encoder) writeSection(tag uint16, section *encoder) {
+	e.writeUint16(tag)
+	e.writeBytes(section.buf.Bytes())
+}
+
+// writeParams function writes the LBPH parameters to the encoder.
+func (e *encoder) writeParams(params Params) {
+	e.writeUint8(params.Radius)
+	e.writeUint8(params.Neighbors)
+	e.writeUint8(params.GridX)
+	e.writeUint8(params.GridY)
+	e.writeUint32(uint32(len(params.Regions)))
+	for _, region := range params.Regions {
+		e.writeFloat64(region.X)
+		e.writeFloat64(region.Y)
+		e.writeFloat64(region.Width)
+		e.writeFloat64(region.Height)
+	}
+	if params.Normalize {
+		e.writeUint8(1)
+	} else {
+		e.writeUint8(0)
+	}
+	e.writeString(string(params.Storage))
+}
+
+// readParams function reads the LBPH parameters written by writeParams
+// using the format of the version passed by parameter.
+func (d *decoder) readParams(version uint16) Params {
+	params := Params{
+		Radius:    d.readUint8(),
+		Neighbors: d.readUint8(),
+		GridX:     d.readUint8(),
+		GridY:     d.readUint8(),
+	}
+	regions := d.readCount(32)
+	for index := 0; index < regions; index++ {
+		params.Regions = append(params.Regions, histogram.Region{
+			X:      d.readFloat64(),
+			Y:      d.readFloat64(),
+			Width:  d.readFloat64(),
+			Height: d.readFloat64(),
+		})
+	}
+	if version >= 2 {
+		params.Normalize = d.readUint8() == 1
+		params.Storage = compact.Storage(d.readString())
+	}
+	return params
+}
+
+// marshalBinary function encodes the model using the binary format.
+func (m *model) marshalBinary() []byte {
+	var e encoder
+	e.buf.Write(modelMagic)
+	e.writeUint16(m.Version)
+
+	// LBPH parameters and images size
+	var section encoder
+	section.writeParams(m.Params)
+	section.writeUint32(uint32(m.Width))
+	section.writeUint32(uint32(m.Height))
+	e.writeSection(sectionParams, &section)
+
+	// Metric name
+	section = encoder{}
+	section.writeString(m.Metric)
+	e.writeSection(sectionMetric, &section)
+
+	// Labels and histograms
+	section = encoder{}
+	section.writeStrings(m.Labels)
+	section.writeMatrix(m.Histograms)
+	e.writeSection(sectionSamples, &section)
+
+	// Sample records (ID and metadata)
+	if m.Samples != nil {
+		section = encoder{}
+		section.writeSamples(m.Samples)
+		e.writeSection(sectionSampleRecords, &section)
+	}
+
+	// Compact histograms
+	if m.CompactHistograms != nil {
+		section = encoder{}
+		section.writeCompact(m.Params.Storage, m.CompactHistograms)
+		e.writeSection(sectionCompactHistograms, &section)
+	}
+
+	// Fitted metric
+	if m.FittedMetric != nil {
+		section = encoder{}
+		section.writeString(m.FittedMetric.Name())
+		if m.FittedMetric.Full {
+			section.writeUint8(1)
+		} else {
+			section.writeUint8(0)
+		}
+		section.writeFloat64s(m.FittedMetric.Variances)
+		section.writeMatrix(m.FittedMetric.InverseCovariance)
+		e.writeSection(sectionFittedMetric, &section)
+	}
+
+	// Calibration
+	if m.Calibration != nil {
+		section = encoder{}
+		section.writeString(m.Calibration.Type)
+		section.writeFloat64(m.Calibration.A)
+		section.writeFloat64(m.Calibration.B)
+		section.writeFloat64s(m.Calibration.Distances)
+		section.writeFloat64s(m.Calibration.Confidences)
+		e.writeSection(sectionCalibration, &section)
+	}
+
+	// Prototypes
+	if len(m.PrototypeHistograms) > 0 {
+		section = encoder{}
+		section.writeStrings(m.PrototypeLabels)
+		section.writeMatrix(m.PrototypeHistograms)
+		e.writeSection(sectionPrototypes, &section)
+	}
+	if m.PrototypeParams != nil {
+		section = encoder{}
+		section.writeString(string(m.PrototypeParams.Mode))
+		section.writeUint32(uint32(m.PrototypeParams.K))
+		e.writeSection(sectionPrototypeParams, &section)
+	}
+
+	// Images (optional)
+	if len(m.Images) > 0 {
+		section = encoder{}
+		section.writeUint32(uint32(len(m.Images)))
+		for _, data := range m.Images {
+			section.writeBytes(data)
+		}
+		e.writeSection(sectionImages, &section)
+	}
+
+	// Mask
+	if m.Mask != nil {
+		section = encoder{}
+		section.writeBytes(m.Mask)
+		e.writeSection(sectionMask, &section)
+	}
+
+	e.writeUint16(sectionEnd)
+	e.writeUint32(crc32.ChecksumIEEE(e.buf.Bytes()))
+
+	return e.buf.Bytes()
+}
+
+// unmarshalBinary function decodes a model encoded using the binary format.
+// It checks the magic, the checksum and the version of the data.
+func unmarshalBinary(data []byte) (*model, error) {
+
+	// Check the magic, version and checksum
+	if len(data) < len(modelMagic)+2+4 || !bytes.Equal(data[:len(modelMagic)], modelMagic) {
+		return nil, errors.New("The data is not a LBPH model")
+	}
+	content := data[:len(data)-4]
+	d := decoder{data: data[len(data)-4:]}
+	if d.readUint32() != crc32.ChecksumIEEE(content) {
+		return nil, errors.New("The model checksum does not match (the model is corrupted)")
+	}
+
+	d = decoder{data: content[len(modelMagic):]}
+	m := &model{Version: d.readUint16()}
+	if m.Version == 0 || m.Version > modelVersion {
+		return nil, errors.New("The model version is not supported")
+	}
+
+	hasParams, hasSamples := false, false
+	for d.err == nil {
+		tag := d.readUint16()
+		if tag == sectionEnd || d.err != nil {
+			break
+		}
+		section := decoder{data: d.readBytes()}
+		if d.err != nil {
+			break
+		}
+
+		switch tag {
+		case sectionParams:
+			m.Params = section.readParams(m.Version)
+			m.Width = int(section.readUint32())
+			m.Height = int(section.readUint32())
+			hasParams = true
+		case sectionMetric:
+			m.Metric = section.readString()
+		case sectionSamples:
+			m.Labels = section.readStrings()
+			m.Histograms = section.readMatrix()
+			hasSamples = true
+		case sectionCompactHistograms:
+			m.CompactHistograms = section.readCompact()
+		case sectionSampleRecords:
+			// The samples section is written after the labels
+			m.Samples = section.readSamples(m.Labels)
+		case sectionFittedMetric:
+			name := section.readString()
+			fitted := metric.Mahalanobis{Full: section.readUint8() == 1}
+			fitted.Variances = section.readFloat64s()
+			fitted.InverseCovariance = section.readMatrix()
+			if section.err == nil && fitted.Name() != name {
+				return nil, errors.New("The fitted metric of the model is not supported")
+			}
+			if fitted.Full {
+				fitted.Variances = nil
+			} else {
+				fitted.InverseCovariance = nil
+			}
+			m.FittedMetric = &fitted
+		case sectionCalibration:
+			m.Calibration = &modelCalibration{
+				Type:        section.readString(),
+				A:           section.readFloat64(),
+				B:           section.readFloat64(),
+				Distances:   section.readFloat64s(),
+				Confidences: section.readFloat64s(),
+			}
+			if len(m.Calibration.Distances) == 0 {
+				m.Calibration.Distances, m.Calibration.Confidences = nil, nil
+			}
+		case sectionPrototypes:
+			m.PrototypeLabels = section.readStrings()
+			m.PrototypeHistograms = section.readMatrix()
+		case sectionPrototypeParams:
+			m.PrototypeParams = &PrototypeParams{
+				Mode: PrototypeMode(section.readString()),
+				K:    int(section.readUint32()),
+			}
+		case sectionImages:
+			count := section.readCount(4)
+			for index := 0; index < count && section.err == nil; index++ {
+				m.Images = append(m.Images, section.readBytes())
+			}
+		case sectionMask:
+			m.Mask = section.readBytes()
+		}
+		// Unknown sections are skipped
+
+		if section.err != nil {
+			return nil, section.err
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if !hasParams || !hasSamples {
+		return nil, errors.New("The model does not contain the parameters or the histograms")
+	}
+	return m, nil
+}
+
+// Save function writes the trained model (LBPH parameters, metric, labels,
+// histograms, fitted metric, calibration, prototypes and mask) to the writer
+// using a versioned binary format with a checksum. The training images are
+// only stored if SaveImages is true.
+func Save(w io.Writer) error {
+	m, err := newModel(trainingData)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(m.marshalBinary())
+	return err
+}
+
+// Load function reads a model written by the Save function and replaces the
+// training data, the LBPH parameters, the Metric and the Mask. The metric
+// must be registered in the metric package. Corrupted models (invalid
+// checksum) and unsupported versions are rejected.
+func Load(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m, err := unmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	return m.apply()
+}
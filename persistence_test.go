@@ -0,0 +1,153 @@
+package lbph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/kelvins/lbph/calibration"
+	"github.com/kelvins/lbph/compact"
+	"github.com/kelvins/lbph/mask"
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resign function replaces the checksum of the model data.
+func resign(data []byte) []byte {
+	signed := append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(signed[len(signed)-4:], crc32.ChecksumIEEE(signed[:len(signed)-4]))
+	return signed
+}
+
+func TestSaveLoad(t *testing.T) {
+	defer func() {
+		Metric = metric.EuclideanDistance
+		Mask = nil
+		SaveImages = false
+		Prototypes = PrototypeParams{Mode: NoPrototype, K: 1}
+	}()
+
+	// The algorithm was not trained yet
+	Init(Params{})
+	var buf bytes.Buffer
+	assert.NotNil(t, Save(&buf))
+
+	images := trainSamples(t)
+	Mask = mask.Ellipse(images[0].Bounds().Max.X, images[0].Bounds().Max.Y)
+	Metric = metric.MahalanobisDistance
+	Prototypes = PrototypeParams{Mode: MeanPrototype}
+	images = trainSamples(t)
+	err := Calibrate(images, GetTrainingData().Labels, calibration.FitIsotonic)
+	assert.Nil(t, err)
+
+	for _, saveImages := range []bool{false, true} {
+		SaveImages = saveImages
+		expected := GetTrainingData()
+		expectedMask := expected.Mask
+		assert.NotNil(t, expectedMask)
+
+		// The Metric and the Mask changed after the training are not saved
+		Metric = metric.CosineDistance
+		Mask = nil
+
+		buf.Reset()
+		assert.Nil(t, Save(&buf))
+
+		// Reset the state before loading the model
+		Init(Params{Radius: 2})
+		Metric = metric.ChiSquare
+		Mask = nil
+
+		assert.Nil(t, Load(bytes.NewReader(buf.Bytes())))
+		loaded := GetTrainingData()
+
+		assert.Equal(t, metric.MahalanobisDistance, Metric)
+		assert.Equal(t, expected.Params, loaded.Params)
+		assert.Equal(t, expected.Width, loaded.Width)
+		assert.Equal(t, expected.Height, loaded.Height)
+		assert.Equal(t, expected.Labels, loaded.Labels)
+		assert.Equal(t, expected.Histograms, loaded.Histograms)
+		assert.Equal(t, expected.Metric, loaded.Metric)
+		assert.Equal(t, expected.Calibration, loaded.Calibration)
+		assert.Equal(t, expected.PrototypeLabels, loaded.PrototypeLabels)
+		assert.Equal(t, expected.PrototypeHistograms, loaded.PrototypeHistograms)
+		assert.Equal(t, expectedMask.Bounds(), Mask.Bounds())
+		assert.True(t, equalMasks(expectedMask, Mask))
+		assert.True(t, equalMasks(expectedMask, loaded.Mask))
+		if saveImages {
+			assert.Equal(t, len(expected.Images), len(loaded.Images))
+		} else {
+			assert.Nil(t, loaded.Images)
+		}
+
+		// The loaded model should predict the same labels
+		for index, img := range images {
+			label, _, err := Predict(img)
+			assert.Nil(t, err)
+			assert.Equal(t, expected.Labels[index], label)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	defer func() { Metric = metric.EuclideanDistance }()
+
+	trainDataset(t)
+	var buf bytes.Buffer
+	assert.Nil(t, Save(&buf))
+	data := buf.Bytes()
+
+	// Unknown sections should be skipped
+	unknown := append([]byte{}, data[:len(data)-6]...)
+	unknown = append(unknown, 0xFF, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x02)
+	unknown = append(unknown, data[len(data)-6:]...)
+	assert.Nil(t, Load(bytes.NewReader(resign(unknown))))
+
+	// Invalid magic
+	invalid := append([]byte{}, data...)
+	invalid[0] = 'X'
+	assert.NotNil(t, Load(bytes.NewReader(resign(invalid))))
+
+	// Unsupported version
+	invalid = append([]byte{}, data...)
+	invalid[4] = 0xFF
+	assert.NotNil(t, Load(bytes.NewReader(resign(invalid))))
+
+	// Corrupted data (checksum does not match)
+	invalid = append([]byte{}, data...)
+	invalid[len(invalid)/2] ^= 0xFF
+	assert.NotNil(t, Load(bytes.NewReader(invalid)))
+
+	// Truncated data
+	assert.NotNil(t, Load(bytes.NewReader(data[:len(data)/2])))
+	assert.NotNil(t, Load(bytes.NewReader(resign(data[:len(data)/2]))))
+	assert.NotNil(t, Load(bytes.NewReader(nil)))
+
+	// Metric not registered
+	custom := metric.New("Custom", func(hist1, hist2 []float64) (float64, error) { return 0, nil })
+	Metric = custom
+	trainDataset(t)
+	buf.Reset()
+	assert.Nil(t, Save(&buf))
+	assert.NotNil(t, Load(bytes.NewReader(buf.Bytes())))
+
+	// The state should not change when the model is invalid
+	assert.Equal(t, "Custom", Metric.Name())
+}
+
+func TestReadParams(t *testing.T) {
+	params := Params{Radius: 1, Neighbors: 8, GridX: 4, GridY: 4, Normalize: true, Storage: compact.Uint16}
+	var e encoder
+	e.writeParams(params)
+
+	d := decoder{data: e.buf.Bytes()}
+	assert.Equal(t, params, d.readParams(modelVersion))
+	assert.Nil(t, d.err)
+
+	// The version 1 has no Normalize and Storage parameters
+	d = decoder{data: e.buf.Bytes()[:8]}
+	assert.Equal(t, Params{Radius: 1, Neighbors: 8, GridX: 4, GridY: 4}, d.readParams(1))
+	assert.Nil(t, d.err)
+}
@@ -0,0 +1,229 @@
+package lbph
+
+import (
+	"errors"
+
+	"github.com/kelvins/lbph/histogram"
+	"github.com/kelvins/lbph/math"
+	"github.com/kelvins/lbph/metric"
+)
+
+// PrototypeMode type defines how the histograms of each label are collapsed
+// into prototypes in the training step.
+type PrototypeMode string
+
+// Prototype modes. NoPrototype (default) compares the images to all training
+// histograms. MeanPrototype uses the mean histogram of each label, MedoidPrototype
+// uses the training histogram closest to the others (based on the Metric) and
+// KMeansPrototype uses up to K centroids per label (k-means clustering).
+const (
+	NoPrototype     PrototypeMode = ""
+	MeanPrototype   PrototypeMode = "Mean"
+	MedoidPrototype PrototypeMode = "Medoid"
+	KMeansPrototype PrototypeMode = "KMeans"
+)
+
+// PrototypeParams struct is used to define the prototype matching mode.
+// K is the number of centroids per label used by the KMeansPrototype mode.
+type PrototypeParams struct {
+	Mode PrototypeMode `json:"mode"`
+	K    int           `json:"k"`
+}
+
+// Prototypes defines the prototype matching mode. It should be defined before
+// calling the Train function. When it is enabled, the Predict step compares the
+// images to the prototypes instead of every training histogram, so it is faster
+// and labels with many images are not over-weighted.
+var Prototypes = PrototypeParams{Mode: NoPrototype, K: 1}
+
+// maxKMeansIterations is the maximum number of iterations of the k-means clustering.
+const maxKMeansIterations = 100
+
+// groupByLabel function returns the unique labels (in order of appearance) and
+// the histograms of each label.
+func groupByLabel(histograms [][]float64, labels []string) ([]string, map[string][][]float64) {
+	var uniqueLabels []string
+	groups := make(map[string][][]float64)
+	for index := 0; index < len(histograms); index++ {
+		if _, ok := groups[labels[index]]; !ok {
+			uniqueLabels = append(uniqueLabels, labels[index])
+		}
+		groups[labels[index]] = append(groups[labels[index]], histograms[index])
+	}
+	return uniqueLabels, groups
+}
+
+// calculatePrototypes function collapses the histograms of each label into
+// prototypes based on the prototype matching mode passed by parameter. It returns
+// nil slices if the prototype matching is disabled.
+func calculatePrototypes(params PrototypeParams, histograms [][]float64, labels []string, selectedMetric metric.Metric) ([][]float64, []string, error) {
+	if params.Mode == NoPrototype {
+		return nil, nil, nil
+	}
+
+	var prototypes [][]float64
+	var prototypeLabels []string
+
+	uniqueLabels, groups := groupByLabel(histograms, labels)
+	for _, label := range uniqueLabels {
+		var labelPrototypes [][]float64
+		var err error
+
+		switch params.Mode {
+		case MeanPrototype:
+			var mean []float64
+			mean, err = math.Mean(groups[label])
+			labelPrototypes = [][]float64{mean}
+		case MedoidPrototype:
+			var medoid []float64
+			medoid, err = calculateMedoid(groups[label], selectedMetric)
+			labelPrototypes = [][]float64{medoid}
+		case KMeansPrototype:
+			labelPrototypes, err = calculateKMeans(groups[label], params.K)
+		default:
+			err = errors.New("Invalid prototype mode selected")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, prototype := range labelPrototypes {
+			prototypes = append(prototypes, prototype)
+			prototypeLabels = append(prototypeLabels, label)
+		}
+	}
+
+	return prototypes, prototypeLabels, nil
+}
+
+// calculateMedoid function returns the histogram with the lowest sum of
+// distances to the other histograms, based on the metric passed by parameter.
+func calculateMedoid(histograms [][]float64, selectedMetric metric.Metric) ([]float64, error) {
+	minIndex := 0
+	minSum := 0.0
+	for index := 0; index < len(histograms); index++ {
+		var sum float64
+		for other := 0; other < len(histograms); other++ {
+			if other == index {
+				continue
+			}
+			distance, err := histogram.Compare(histograms[index], histograms[other], selectedMetric)
+			if err != nil {
+				return nil, err
+			}
+			sum += distance
+		}
+
+		if index == 0 || sum < minSum {
+			minIndex = index
+			minSum = sum
+		}
+	}
+	return histograms[minIndex], nil
+}
+
+// calculateKMeans function returns up to K centroids of the histograms using the
+// k-means clustering (euclidean distance). The initial centroids are selected
+// deterministically: the first histogram and then the farthest ones.
+func calculateKMeans(histograms [][]float64, k int) ([][]float64, error) {
+	if k <= 0 {
+		return nil, errors.New("The K parameter should be higher than 0")
+	}
+	if k > len(histograms) {
+		k = len(histograms)
+	}
+
+	// Select the initial centroids (farthest-point initialization).
+	centroids := [][]float64{append([]float64{}, histograms[0]...)}
+	for len(centroids) < k {
+		farthestIndex := 0
+		farthestDistance := -1.0
+		for index, hist := range histograms {
+			closest, err := closestCentroid(hist, centroids)
+			if err != nil {
+				return nil, err
+			}
+			if closest.distance > farthestDistance {
+				farthestIndex = index
+				farthestDistance = closest.distance
+			}
+		}
+		centroids = append(centroids, append([]float64{}, histograms[farthestIndex]...))
+	}
+
+	assignments := make([]int, len(histograms))
+	for iteration := 0; iteration < maxKMeansIterations; iteration++ {
+		// Assign each histogram to the closest centroid.
+		changed := iteration == 0
+		for index, hist := range histograms {
+			closest, err := closestCentroid(hist, centroids)
+			if err != nil {
+				return nil, err
+			}
+			if closest.index != assignments[index] {
+				assignments[index] = closest.index
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		// Update the centroids (empty clusters keep the previous centroid).
+		for cluster := range centroids {
+			var members [][]float64
+			for index, assignment := range assignments {
+				if assignment == cluster {
+					members = append(members, histograms[index])
+				}
+			}
+			if len(members) == 0 {
+				continue
+			}
+			mean, err := math.Mean(members)
+			if err != nil {
+				return nil, err
+			}
+			centroids[cluster] = mean
+		}
+	}
+
+	return centroids, nil
+}
+
+// centroidDistance struct stores the index of a centroid and its distance.
+type centroidDistance struct {
+	index    int
+	distance float64
+}
+
+// closestCentroid function returns the centroid closest to the histogram.
+func closestCentroid(hist []float64, centroids [][]float64) (centroidDistance, error) {
+	var closest centroidDistance
+	for index, centroid := range centroids {
+		distance, err := math.EuclideanDistance(hist, centroid)
+		if err != nil {
+			return closest, err
+		}
+		if index == 0 || distance < closest.distance {
+			closest = centroidDistance{index: index, distance: distance}
+		}
+	}
+	return closest, nil
+}
+
+// getGallery function returns the histograms (and labels) compared in the
+// Predict step: the prototypes if they were calculated, otherwise all the
+// training histograms.
+func getGallery() gallery {
+	if len(trainingData.PrototypeHistograms) > 0 {
+		return gallery{labels: trainingData.PrototypeLabels, histograms: trainingData.PrototypeHistograms}
+	}
+	return gallery{
+		labels:     trainingData.Labels,
+		samples:    trainingData.Samples,
+		histograms: trainingData.Histograms,
+		compact:    trainingData.CompactHistograms,
+		mapped:     trainingData.gallery,
+	}
+}
@@ -0,0 +1,101 @@
+package lbph
+
+import (
+	"image"
+	"testing"
+
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateKMeans(t *testing.T) {
+	histograms := [][]float64{{0, 0}, {0, 1}, {10, 10}, {10, 11}, {0, 2}}
+
+	centroids, err := calculateKMeans(histograms, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]float64{{0, 1}, {10, 10.5}}, centroids)
+
+	// K higher than the number of histograms
+	centroids, err = calculateKMeans(histograms[:2], 5)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]float64{{0, 0}, {0, 1}}, centroids)
+
+	_, err = calculateKMeans(histograms, 0)
+	assert.NotNil(t, err)
+}
+
+func TestCalculateMedoid(t *testing.T) {
+	histograms := [][]float64{{0, 0}, {0, 1}, {0, 2}, {0, 3}, {0, 10}}
+
+	medoid, err := calculateMedoid(histograms, metric.EuclideanDistance)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{0, 2}, medoid)
+
+	_, err = calculateMedoid(histograms, metric.Name("InvalidMetric"))
+	assert.NotNil(t, err)
+}
+
+func TestPrototypes(t *testing.T) {
+	defer func() { Prototypes = PrototypeParams{Mode: NoPrototype, K: 1} }()
+	Init(Params{})
+
+	paths := []string{
+		"./dataset/train/1.png", "./dataset/train/2.png", "./dataset/train/3.png",
+		"./dataset/test/2.png", "./dataset/test/3.png",
+	}
+	labels := []string{"rocks", "grass", "wood", "rocks", "grass"}
+
+	var images []image.Image
+	for index := 0; index < len(paths); index++ {
+		img, err := LoadImage(paths[index])
+		assert.Nil(t, err)
+		images = append(images, img)
+	}
+
+	test, err := LoadImage("./dataset/test/1.png")
+	assert.Nil(t, err)
+
+	// Table tests
+	var tTable = []struct {
+		params PrototypeParams
+		size   int
+	}{
+		{PrototypeParams{Mode: NoPrototype}, 0},
+		{PrototypeParams{Mode: MeanPrototype}, 3},
+		{PrototypeParams{Mode: MedoidPrototype}, 3},
+		{PrototypeParams{Mode: KMeansPrototype, K: 1}, 3},
+		{PrototypeParams{Mode: KMeansPrototype, K: 2}, 5},
+	}
+
+	// Test with all values in the table
+	for _, pair := range tTable {
+		Prototypes = pair.params
+		err = Train(images, labels)
+		assert.Nil(t, err)
+
+		trainData := GetTrainingData()
+		assert.Equal(t, pair.size, len(trainData.PrototypeHistograms))
+		assert.Equal(t, pair.size, len(trainData.PrototypeLabels))
+
+		label, _, err := Predict(test)
+		assert.Nil(t, err)
+		assert.Equal(t, "wood", label)
+
+		predictions, err := PredictTopK(test, 10)
+		assert.Nil(t, err)
+		if pair.size > 0 {
+			assert.Equal(t, pair.size, len(predictions))
+		}
+	}
+
+	// The prototypes should be grouped by label (in order of appearance)
+	Prototypes = PrototypeParams{Mode: MeanPrototype}
+	err = Train(images, labels)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rocks", "grass", "wood"}, GetTrainingData().PrototypeLabels)
+
+	Prototypes = PrototypeParams{Mode: PrototypeMode("Invalid")}
+	err = Train(images, labels)
+	assert.NotNil(t, err)
+}
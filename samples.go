@@ -0,0 +1,269 @@
+package lbph
+
+import (
+	"errors"
+	"image"
+	"sort"
+	"strconv"
+)
+
+// Sample struct is a training record: an ID (e.g. the ID of the person in other
+// systems), the label and optional metadata (e.g. enrollment date, source camera or
+// image hash). It is stored with the histogram of the image and returned in the
+// Prediction struct by the PredictTopK function.
+type Sample struct {
+	ID       int64             `json:"id"`
+	Label    string            `json:"label"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// TrainSamples function is used for training the LBPH algorithm based on the images
+// and the sample records passed by parameter (same as the Train function, using the
+// label of each sample). The samples are stored in the training data and their IDs
+// must be unique. The images are only stored if RetainUpdateImages is true.
+func TrainSamples(images []image.Image, samples []Sample) error {
+	if err := checkUniqueIDs(samples); err != nil {
+		return err
+	}
+
+	labels := make([]string, len(samples))
+	for index, sample := range samples {
+		labels[index] = sample.Label
+	}
+
+	if err := train(images, labels, RetainUpdateImages); err != nil {
+		return err
+	}
+	trainingData.Samples = append([]Sample{}, samples...)
+	return nil
+}
+
+// UpdateSamples function adds the images and the sample records passed by parameter
+// to the trained algorithm (same as the Update function, using the label of each sample).
+// The algorithm must have been trained using sample records (e.g. TrainSamples) and
+// the IDs must not be found in the training data.
+func UpdateSamples(images []image.Image, samples []Sample) error {
+	labels := make([]string, len(samples))
+	for index, sample := range samples {
+		labels[index] = sample.Label
+	}
+	return update(images, labels, samples)
+}
+
+// checkSampleRecords function checks if the samples (nil if only the labels are passed)
+// can be added to the training data. The sample records and the labels can not be
+// mixed, as the histograms added using labels would have no ID, and the IDs must be unique.
+func checkSampleRecords(data *TrainingData, samples []Sample) error {
+	if data.Samples == nil && samples != nil {
+		return errors.New("The algorithm was not trained using sample records (use the Update function)")
+	}
+	if data.Samples != nil && samples == nil {
+		return errors.New("The algorithm was trained using sample records (use the UpdateSamples function)")
+	}
+	return checkUniqueIDs(data.Samples, samples)
+}
+
+// checkUniqueIDs function checks if the IDs of the samples (of all slices) are unique,
+// so the RemoveSamplesByID function and the predictions refer to a single sample.
+func checkUniqueIDs(samples ...[]Sample) error {
+	ids := make(map[int64]bool)
+	for _, slice := range samples {
+		for _, sample := range slice {
+			if ids[sample.ID] {
+				return errors.New("The sample ID " + strconv.FormatInt(sample.ID, 10) + " is duplicated")
+			}
+			ids[sample.ID] = true
+		}
+	}
+	return nil
+}
+
+// rebuildTrainingData function replaces the training data keeping only the samples
+// (images, labels and histograms) for which the keep function returns true. The
+// metric parameters and the prototypes are recalculated (using the metric and the
+// prototype matching mode of the training data) and the calibration is kept.
+// If no sample is kept, the training data is reset (the algorithm needs to be trained).
+func rebuildTrainingData(labels []string, keep func(index int) bool) error {
+	var keptImages []image.Image
+	var keptLabels []string
+	var keptHistograms [][]float64
+	var keptSamples []Sample
+
+	// The images are optional, so they are only kept if all of them are stored.
+	histograms, err := trainingData.getHistograms()
+	if err != nil {
+		return err
+	}
+	hasImages := len(trainingData.Images) == len(trainingData.Labels)
+
+	for index := 0; index < len(labels); index++ {
+		if !keep(index) {
+			continue
+		}
+		if hasImages {
+			keptImages = append(keptImages, trainingData.Images[index])
+		}
+		keptLabels = append(keptLabels, labels[index])
+		keptHistograms = append(keptHistograms, histograms[index])
+		if trainingData.Samples != nil {
+			sample := trainingData.Samples[index]
+			sample.Label = labels[index]
+			keptSamples = append(keptSamples, sample)
+		}
+	}
+
+	// All samples were removed.
+	if len(keptLabels) == 0 {
+		trainingData = nil
+		return nil
+	}
+
+	updatedData, err := newTrainingData(trainingData, keptImages, keptLabels, keptHistograms, trainingData.Width, trainingData.Height)
+	if err != nil {
+		return err
+	}
+
+	// Keep the calibration fitted before the change.
+	updatedData.Calibration = trainingData.Calibration
+	updatedData.Samples = keptSamples
+	trainingData = updatedData
+
+	return nil
+}
+
+// RemoveLabel function removes all samples (images, labels and histograms) of
+// the label passed by parameter from the training data and returns the number
+// of removed samples. If all samples are removed, the training data is reset.
+func RemoveLabel(label string) (int, error) {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return 0, errors.New("The algorithm was not trained yet")
+	}
+
+	removed := 0
+	for _, current := range trainingData.Labels {
+		if current == label {
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, errors.New("The label was not found in the training data")
+	}
+
+	labels := trainingData.Labels
+	err := rebuildTrainingData(labels, func(index int) bool {
+		return labels[index] != label
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// RemoveSamplesByID function removes the samples with the IDs passed by parameter
+// (see the TrainSamples function) from the training data and returns the number of
+// removed samples. If all samples are removed, the training data is reset.
+func RemoveSamplesByID(ids ...int64) (int, error) {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return 0, errors.New("The algorithm was not trained yet")
+	}
+	if trainingData.Samples == nil {
+		return 0, errors.New("The algorithm was not trained using sample records")
+	}
+
+	removedIDs := make(map[int64]bool)
+	for _, id := range ids {
+		removedIDs[id] = true
+	}
+
+	removed := 0
+	for _, sample := range trainingData.Samples {
+		if removedIDs[sample.ID] {
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, errors.New("The IDs were not found in the training data")
+	}
+
+	samples := trainingData.Samples
+	err := rebuildTrainingData(trainingData.Labels, func(index int) bool {
+		return !removedIDs[samples[index].ID]
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// RemoveSamples function removes the samples with the indexes passed by parameter
+// (indexes of the TrainingData slices) from the training data. The indexes of the
+// following samples are shifted. If all samples are removed, the training data is reset.
+func RemoveSamples(indexes ...int) error {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return errors.New("The algorithm was not trained yet")
+	}
+
+	removed := make(map[int]bool)
+	for _, index := range indexes {
+		if index < 0 || index >= len(trainingData.Labels) {
+			return errors.New("Invalid sample index passed to the RemoveSamples function")
+		}
+		removed[index] = true
+	}
+
+	return rebuildTrainingData(trainingData.Labels, func(index int) bool {
+		return !removed[index]
+	})
+}
+
+// RenameLabel function renames all samples of the label (oldLabel) to the new one
+// (newLabel). If the new label already exists, the samples of both labels are merged.
+func RenameLabel(oldLabel, newLabel string) error {
+
+	// Check if we have data in the trainingData struct.
+	if trainingData == nil {
+		return errors.New("The algorithm was not trained yet")
+	}
+
+	// Copy the labels, so the user slice is not changed.
+	labels := make([]string, len(trainingData.Labels))
+	found := false
+	for index, label := range trainingData.Labels {
+		if label == oldLabel {
+			label = newLabel
+			found = true
+		}
+		labels[index] = label
+	}
+	if !found {
+		return errors.New("The label was not found in the training data")
+	}
+
+	return rebuildTrainingData(labels, func(index int) bool {
+		return true
+	})
+}
+
+// GetLabels function returns the unique labels of the training data (sorted).
+func GetLabels() []string {
+	if trainingData == nil {
+		return nil
+	}
+
+	unique := make(map[string]bool)
+	var labels []string
+	for _, label := range trainingData.Labels {
+		if !unique[label] {
+			unique[label] = true
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
@@ -0,0 +1,263 @@
+package lbph
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trainSamples function trains the algorithm using the training and test images.
+func trainSamples(t *testing.T) []image.Image {
+	Init(Params{})
+
+	paths := []string{
+		"./dataset/train/1.png", "./dataset/train/2.png", "./dataset/train/3.png",
+		"./dataset/test/1.png", "./dataset/test/2.png",
+	}
+	labels := []string{"rocks", "grass", "wood", "wood", "rocks"}
+
+	var images []image.Image
+	for index := 0; index < len(paths); index++ {
+		img, err := LoadImage(paths[index])
+		assert.Nil(t, err)
+		images = append(images, img)
+	}
+
+	err := Train(images, labels)
+	assert.Nil(t, err)
+	return images
+}
+
+func TestRemoveLabel(t *testing.T) {
+	Init(Params{})
+	_, err := RemoveLabel("rocks")
+	assert.NotNil(t, err)
+
+	images := trainSamples(t)
+	histograms := GetTrainingData().Histograms
+
+	removed, err := RemoveLabel("rocks")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, removed)
+
+	trainData := GetTrainingData()
+	assert.Equal(t, []string{"grass", "wood", "wood"}, trainData.Labels)
+	assert.Equal(t, []image.Image{images[1], images[2], images[3]}, trainData.Images)
+	assert.Equal(t, [][]float64{histograms[1], histograms[2], histograms[3]}, trainData.Histograms)
+	assert.Equal(t, []string{"grass", "wood"}, GetLabels())
+
+	_, err = RemoveLabel("rocks")
+	assert.NotNil(t, err)
+
+	// Remove all samples
+	_, err = RemoveLabel("grass")
+	assert.Nil(t, err)
+	_, err = RemoveLabel("wood")
+	assert.Nil(t, err)
+	assert.Nil(t, GetLabels())
+
+	_, _, err = Predict(images[0])
+	assert.NotNil(t, err)
+}
+
+func TestRemoveSamples(t *testing.T) {
+	Init(Params{})
+	err := RemoveSamples(0)
+	assert.NotNil(t, err)
+
+	trainSamples(t)
+	histograms := GetTrainingData().Histograms
+
+	err = RemoveSamples(5)
+	assert.NotNil(t, err)
+
+	err = RemoveSamples(-1)
+	assert.NotNil(t, err)
+
+	err = RemoveSamples(0, 3, 3)
+	assert.Nil(t, err)
+
+	trainData := GetTrainingData()
+	assert.Equal(t, []string{"grass", "wood", "rocks"}, trainData.Labels)
+	assert.Equal(t, [][]float64{histograms[1], histograms[2], histograms[4]}, trainData.Histograms)
+	assert.Equal(t, 3, len(trainData.Images))
+}
+
+func TestRenameLabel(t *testing.T) {
+	Init(Params{})
+	err := RenameLabel("rocks", "stones")
+	assert.NotNil(t, err)
+
+	images := trainSamples(t)
+	labels := GetTrainingData().Labels
+
+	err = RenameLabel("rocks", "stones")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"stones", "grass", "wood", "wood", "stones"}, GetTrainingData().Labels)
+
+	// The user slice should not be changed
+	assert.Equal(t, "rocks", labels[0])
+
+	label, _, err := Predict(images[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "stones", label)
+
+	// Merge two labels
+	err = RenameLabel("grass", "wood")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"stones", "wood"}, GetLabels())
+
+	err = RenameLabel("grass", "wood")
+	assert.NotNil(t, err)
+}
+
+func TestRemoveKeepsSettings(t *testing.T) {
+	defer func() {
+		Metric = metric.EuclideanDistance
+		Prototypes = PrototypeParams{Mode: NoPrototype, K: 1}
+	}()
+
+	// Train using the Mahalanobis distance and the mean prototypes
+	Metric = metric.MahalanobisDistance
+	Prototypes = PrototypeParams{Mode: MeanPrototype, K: 1}
+	trainSamples(t)
+
+	// The metric and the prototypes of the training data are recalculated
+	// even if the current settings have changed
+	Metric = metric.CosineDistance
+	Prototypes = PrototypeParams{Mode: NoPrototype, K: 1}
+	_, err := RemoveLabel("grass")
+	assert.Nil(t, err)
+	assert.Nil(t, RenameLabel("wood", "stone"))
+
+	trainData := GetTrainingData()
+	assert.Equal(t, metric.MahalanobisDistance, trainData.SelectedMetric)
+	assert.Equal(t, string(metric.MahalanobisDistance), trainData.Metric.Name())
+	assert.Equal(t, MeanPrototype, trainData.Prototypes.Mode)
+	assert.Equal(t, []string{"rocks", "stone"}, trainData.PrototypeLabels)
+
+	// The prototype matching mode is stored in the models
+	Metric = metric.MahalanobisDistance
+	var buf bytes.Buffer
+	assert.Nil(t, Save(&buf))
+	assert.Nil(t, Load(&buf))
+	assert.Equal(t, trainData.Prototypes, GetTrainingData().Prototypes)
+}
+
+func TestSampleRecords(t *testing.T) {
+	defer Init(Params{})
+
+	images := trainSamples(t)
+	samples := []Sample{
+		{ID: 10, Label: "rocks", Metadata: map[string]string{"camera": "front", "date": "2026-01-02"}},
+		{ID: 20, Label: "grass"},
+		{ID: 30, Label: "wood", Metadata: map[string]string{"camera": "back"}},
+	}
+
+	// Invalid sizes
+	err := TrainSamples(images, samples[:2])
+	assert.NotNil(t, err)
+
+	err = TrainSamples(images[:3], samples)
+	assert.Nil(t, err)
+	assert.Equal(t, samples, GetTrainingData().Samples)
+	assert.Equal(t, []string{"rocks", "grass", "wood"}, GetTrainingData().Labels)
+
+	predictions, err := PredictTopK(images[2], 1)
+	assert.Nil(t, err)
+	assert.Equal(t, samples[2], predictions[0].Sample)
+
+	// The labels can not be mixed with the samples
+	err = Update(images[4:], []string{"rocks"})
+	assert.NotNil(t, err)
+	err = UpdateSamples(images[3:], []Sample{{ID: 40, Label: "wood"}, {ID: 0, Label: "rocks"}})
+	assert.Nil(t, err)
+	trainData := GetTrainingData()
+	assert.Equal(t, []int64{10, 20, 30, 40, 0}, []int64{
+		trainData.Samples[0].ID, trainData.Samples[1].ID, trainData.Samples[2].ID,
+		trainData.Samples[3].ID, trainData.Samples[4].ID,
+	})
+	assert.Equal(t, "rocks", trainData.Samples[4].Label)
+
+	// The samples are preserved by the binary and JSON formats
+	for _, format := range []string{"binary", "json"} {
+		var buf bytes.Buffer
+		if format == "binary" {
+			assert.Nil(t, Save(&buf))
+		} else {
+			assert.Nil(t, SaveJSON(&buf))
+		}
+		Init(Params{})
+		if format == "binary" {
+			assert.Nil(t, Load(&buf))
+		} else {
+			assert.Nil(t, LoadJSON(&buf))
+		}
+		assert.Equal(t, trainData.Samples, GetTrainingData().Samples)
+	}
+
+	// The labels of the samples are renamed
+	err = RenameLabel("wood", "stone")
+	assert.Nil(t, err)
+	assert.Equal(t, "stone", GetTrainingData().Samples[2].Label)
+
+	_, err = RemoveSamplesByID(50)
+	assert.NotNil(t, err)
+
+	removed, err := RemoveSamplesByID(10, 40, 50)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []string{"grass", "stone", "rocks"}, GetTrainingData().Labels)
+	assert.Equal(t, int64(30), GetTrainingData().Samples[1].ID)
+
+	// Trained without samples
+	trainSamples(t)
+	assert.Nil(t, GetTrainingData().Samples)
+	_, err = RemoveSamplesByID(10)
+	assert.NotNil(t, err)
+}
+
+func TestSamplesNotMixed(t *testing.T) {
+	defer Init(Params{})
+
+	// The histograms trained using labels have no ID, so they can not be removed by ID
+	images := trainSamples(t)
+	err := UpdateSamples(images[:1], []Sample{{ID: 1, Label: "rocks"}})
+	assert.NotNil(t, err)
+	_, err = RemoveSamplesByID(0)
+	assert.NotNil(t, err)
+	assert.Equal(t, 5, len(GetTrainingData().Labels))
+	assert.Nil(t, GetTrainingData().Samples)
+
+	// Update using labels keeps the training data without samples
+	err = Update(images[:1], []string{"rocks"})
+	assert.Nil(t, err)
+	assert.Nil(t, GetTrainingData().Samples)
+}
+
+func TestDuplicateSampleIDs(t *testing.T) {
+	defer Init(Params{})
+	images := trainSamples(t)
+
+	// Duplicated IDs in the training samples
+	err := TrainSamples(images[:2], []Sample{{ID: 1, Label: "rocks"}, {ID: 1, Label: "grass"}})
+	assert.EqualError(t, err, "The sample ID 1 is duplicated")
+
+	assert.Nil(t, TrainSamples(images[:2], []Sample{{ID: 1, Label: "rocks"}, {ID: 2, Label: "grass"}}))
+
+	// Duplicated IDs in the new samples or found in the training data
+	err = UpdateSamples(images[2:4], []Sample{{ID: 3, Label: "wood"}, {ID: 3, Label: "wood"}})
+	assert.EqualError(t, err, "The sample ID 3 is duplicated")
+	err = UpdateSamples(images[2:3], []Sample{{ID: 2, Label: "wood"}})
+	assert.EqualError(t, err, "The sample ID 2 is duplicated")
+	assert.Equal(t, 2, len(GetTrainingData().Samples))
+
+	assert.Nil(t, UpdateSamples(images[2:3], []Sample{{ID: 3, Label: "wood"}}))
+	removed, err := RemoveSamplesByID(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+}
@@ -0,0 +1,74 @@
+package lbph
+
+import (
+	"github.com/kelvins/lbph/compact"
+	"github.com/kelvins/lbph/histogram"
+	"github.com/kelvins/lbph/metric"
+)
+
+// gallery struct stores the histograms (and labels) compared in the Predict step.
+// The histograms are stored in one of the slices, based on the storage.
+type gallery struct {
+	labels     []string
+	samples    []Sample
+	histograms [][]float64
+	compact    []compact.Histogram
+	mapped     *Gallery
+}
+
+// size function returns the number of histograms in the gallery.
+func (g gallery) size() int {
+	return len(g.labels)
+}
+
+// compare function compares the histogram to the histogram of the gallery at the index.
+func (g gallery) compare(hist []float64, index int, selectedMetric metric.Metric) (float64, error) {
+	if g.mapped != nil {
+		return g.mapped.distance(hist, index, selectedMetric)
+	}
+	if g.compact != nil {
+		return compact.Distance(hist, g.compact[index], selectedMetric)
+	}
+	return histogram.Compare(hist, g.histograms[index], selectedMetric)
+}
+
+// isCompact function checks if the storage is a compact storage (not Float64).
+func isCompact(storage compact.Storage) bool {
+	return storage != "" && storage != compact.Float64
+}
+
+// compactHistograms function converts the histograms to the compact storage
+// defined in the Params. The Histograms slice is replaced by CompactHistograms.
+func (data *TrainingData) compactHistograms() error {
+	if !isCompact(data.Params.Storage) {
+		return nil
+	}
+
+	converted := make([]compact.Histogram, len(data.Histograms))
+	for index, hist := range data.Histograms {
+		var err error
+		if converted[index], err = compact.New(hist, data.Params.Storage); err != nil {
+			return err
+		}
+	}
+	data.CompactHistograms = converted
+	data.Histograms = nil
+	return nil
+}
+
+// getHistograms function returns the training histograms. The compact (and
+// memory-mapped) histograms are decoded, so it should not be used by the Predict step.
+// It returns an error if the memory-mapped gallery was closed.
+func (data *TrainingData) getHistograms() ([][]float64, error) {
+	if data.gallery != nil {
+		return data.gallery.histograms()
+	}
+	if data.CompactHistograms == nil {
+		return data.Histograms, nil
+	}
+	histograms := make([][]float64, len(data.CompactHistograms))
+	for index, hist := range data.CompactHistograms {
+		histograms[index] = hist.Float64s()
+	}
+	return histograms, nil
+}
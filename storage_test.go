@@ -0,0 +1,82 @@
+package lbph
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kelvins/lbph/compact"
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactStorage(t *testing.T) {
+	defer func() {
+		Init(Params{})
+		Metric = metric.EuclideanDistance
+	}()
+	Metric = metric.ChiSquare
+
+	// Predictions using the float64 storage
+	images := trainSamples(t)
+	histograms := GetTrainingData().Histograms
+	var expected []Prediction
+	for _, img := range images {
+		predictions, err := PredictTopK(img, 1)
+		assert.Nil(t, err)
+		expected = append(expected, predictions[0])
+	}
+
+	// Invalid storage
+	Init(Params{Storage: compact.Storage("Invalid")})
+	err := Train(images, GetLabels())
+	assert.NotNil(t, err)
+
+	for _, storage := range []compact.Storage{compact.Float32, compact.Uint16, compact.Sparse} {
+		Init(Params{Storage: storage})
+		labels := []string{"rocks", "grass", "wood", "wood", "rocks"}
+		err := Train(images, labels)
+		assert.Nil(t, err)
+
+		trainData := GetTrainingData()
+		assert.Nil(t, trainData.Histograms)
+		assert.Equal(t, len(labels), len(trainData.CompactHistograms))
+		assert.Equal(t, storage, trainData.CompactHistograms[0].Storage())
+		assert.InDeltaSlice(t, histograms[0], trainData.CompactHistograms[0].Float64s(), 1e-3)
+
+		for index, img := range images {
+			predictions, err := PredictTopK(img, 1)
+			assert.Nil(t, err)
+			assert.Equal(t, expected[index].Label, predictions[0].Label)
+			assert.InDelta(t, expected[index].Distance, predictions[0].Distance, 1e-3)
+		}
+
+		// The storage is preserved by the binary and JSON formats
+		var buf bytes.Buffer
+		assert.Nil(t, Save(&buf))
+		Init(Params{})
+		assert.Nil(t, Load(&buf))
+		assert.Equal(t, trainData.CompactHistograms, GetTrainingData().CompactHistograms)
+		assert.Equal(t, storage, GetTrainingData().Params.Storage)
+
+		buf.Reset()
+		assert.Nil(t, SaveJSON(&buf))
+		Init(Params{})
+		assert.Nil(t, LoadJSON(&buf))
+		assert.Equal(t, trainData.CompactHistograms, GetTrainingData().CompactHistograms)
+
+		// Update and remove samples
+		err = Update(images[:1], []string{"rocks"})
+		assert.Nil(t, err)
+		assert.Equal(t, 6, len(GetTrainingData().CompactHistograms))
+
+		_, err = RemoveLabel("rocks")
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(GetTrainingData().CompactHistograms))
+		assert.Nil(t, GetTrainingData().Histograms)
+
+		label, _, err := Predict(images[2])
+		assert.Nil(t, err)
+		assert.Equal(t, "wood", label)
+	}
+}
@@ -0,0 +1,34 @@
+package lbph
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrUnknown is returned by the Predict and PredictKNN functions when the distance
+// to the closest training image is higher than the threshold (open-set rejection).
+// In this case the label is empty and the distance is the closest one.
+var ErrUnknown = errors.New("The image does not match any label (the distance is higher than the threshold)")
+
+// Threshold is the maximum distance accepted in the Predict step. If the distance
+// to the closest training image is higher, the image is considered unknown.
+// The default value is +Inf (no rejection), as in OpenCV.
+var Threshold = math.Inf(1)
+
+// LabelThresholds defines optional thresholds by label. If a label has
+// a threshold defined it is used instead of the global Threshold.
+var LabelThresholds map[string]float64
+
+// checkThreshold function returns ErrUnknown if the distance passed by parameter
+// is higher than the threshold defined for the label.
+func checkThreshold(label string, distance float64) error {
+	threshold := Threshold
+	if labelThreshold, ok := LabelThresholds[label]; ok {
+		threshold = labelThreshold
+	}
+
+	if distance > threshold {
+		return ErrUnknown
+	}
+	return nil
+}
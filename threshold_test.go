@@ -0,0 +1,54 @@
+package lbph
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreshold(t *testing.T) {
+	trainDataset(t)
+	defer func() {
+		Threshold = math.Inf(1)
+		LabelThresholds = nil
+		Decision = KNN{K: 1, Voting: MajorityVoting}
+	}()
+
+	img, err := LoadImage("./dataset/test/1.png")
+	assert.Nil(t, err)
+
+	label, distance, err := Predict(img)
+	assert.Nil(t, err)
+	assert.Equal(t, "wood", label)
+
+	// Global threshold
+	Threshold = distance / 2
+	label, rejectedDistance, err := Predict(img)
+	assert.Equal(t, ErrUnknown, err)
+	assert.Equal(t, "", label)
+	assert.Equal(t, distance, rejectedDistance)
+
+	// k-NN decision mode
+	Decision = KNN{K: 3, Voting: MajorityVoting}
+	result, err := PredictKNN(img)
+	assert.Equal(t, ErrUnknown, err)
+	assert.Equal(t, "", result.Label)
+	assert.Equal(t, distance, result.Distance)
+
+	label, _, err = Predict(img)
+	assert.Equal(t, ErrUnknown, err)
+	assert.Equal(t, "", label)
+	Decision = KNN{K: 1, Voting: MajorityVoting}
+
+	// The label threshold has precedence over the global threshold
+	LabelThresholds = map[string]float64{"wood": distance}
+	label, _, err = Predict(img)
+	assert.Nil(t, err)
+	assert.Equal(t, "wood", label)
+
+	Threshold = math.Inf(1)
+	LabelThresholds = map[string]float64{"wood": distance - 1}
+	_, _, err = Predict(img)
+	assert.Equal(t, ErrUnknown, err)
+}
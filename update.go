@@ -0,0 +1,134 @@
+package lbph
+
+import (
+	"errors"
+	"image"
+
+	"github.com/kelvins/lbph/mask"
+)
+
+// equalParams function checks if two Params structs are equal (including the regions).
+func equalParams(params1, params2 Params) bool {
+	if params1.Radius != params2.Radius || params1.Neighbors != params2.Neighbors ||
+		params1.GridX != params2.GridX || params1.GridY != params2.GridY ||
+		params1.Normalize != params2.Normalize || params1.Storage != params2.Storage ||
+		len(params1.Regions) != len(params2.Regions) {
+		return false
+	}
+	for index := range params1.Regions {
+		if params1.Regions[index] != params2.Regions[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalMasks function checks if two masks (optional) have the same size and weights.
+func equalMasks(mask1, mask2 image.Image) bool {
+	if mask1 == nil || mask2 == nil {
+		return mask1 == nil && mask2 == nil
+	}
+	if mask1.Bounds().Size() != mask2.Bounds().Size() {
+		return false
+	}
+
+	weights1, weights2 := mask.FromImage(mask1), mask.FromImage(mask2)
+	for x := range weights1 {
+		for y := range weights1[x] {
+			if weights1[x][y] != weights2[x][y] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Update function is used to add new images and labels to the trained algorithm without
+// retraining it from scratch (only the histograms of the new images are calculated).
+// The images must have the same size as the training images and the LBPH parameters
+// and the Mask must not have changed. The metric parameters (of the metric used to train the
+// algorithm, even if the Metric has changed) and the prototypes are recalculated,
+// but the calibration is kept. If the algorithm was not trained yet, it is trained
+// using the images. The images are only stored if RetainUpdateImages is true. If the
+// algorithm was trained using sample records, the UpdateSamples function must be used.
+func Update(images []image.Image, labels []string) error {
+	return update(images, labels, nil)
+}
+
+// update function adds the images to the training data (see the Update function).
+// The samples are optional (nil), otherwise they have the same size as the labels.
+func update(images []image.Image, labels []string, samples []Sample) error {
+
+	// If the algorithm was not trained yet, train it.
+	if trainingData == nil {
+		if samples != nil {
+			return TrainSamples(images, samples)
+		}
+		return train(images, labels, RetainUpdateImages)
+	}
+
+	// Check if the slices are not empty.
+	if len(images) == 0 || len(labels) == 0 {
+		return errors.New("At least one of the slices is empty")
+	}
+
+	// Check if the images and labels slices have the same size.
+	if len(images) != len(labels) {
+		return errors.New("The slices have different sizes")
+	}
+
+	// Check if the sample records are used as in the training step.
+	if err := checkSampleRecords(trainingData, samples); err != nil {
+		return err
+	}
+
+	// Check if the images are compatible with the training data.
+	if !equalParams(trainingData.Params, lbphParams) {
+		return errors.New("The LBPH parameters have changed since the algorithm was trained")
+	}
+	if !equalMasks(trainingData.Mask, Mask) {
+		return errors.New("The mask has changed since the algorithm was trained")
+	}
+
+	// Calculate the histograms of the new images (in parallel). The images must have
+	// the size of the training images (unknown (0) for the imported models).
+	width, height := trainingData.Width, trainingData.Height
+	if width == 0 {
+		width, height = getImagesSize(images)
+	}
+	histograms, err := extractHistograms(images, width, height)
+	if err != nil {
+		return err
+	}
+	trainingHistograms, err := trainingData.getHistograms()
+	if err != nil {
+		return err
+	}
+	if len(histograms[0]) != len(trainingHistograms[0]) {
+		return errors.New("The histograms have a different size from the training histograms")
+	}
+
+	// Append the new data (the slices are copied, so the user slices are not changed).
+	// The images are only kept if they are retained and the training images are
+	// available (e.g. they are not available for the loaded models).
+	var updatedImages []image.Image
+	if RetainUpdateImages && len(trainingData.Images) == len(trainingData.Labels) {
+		updatedImages = append(append([]image.Image{}, trainingData.Images...), images...)
+	}
+	updatedLabels := append(append([]string{}, trainingData.Labels...), labels...)
+	updatedHistograms := append(append([][]float64{}, trainingHistograms...), histograms...)
+
+	updatedData, err := newTrainingData(trainingData, updatedImages, updatedLabels, updatedHistograms, width, height)
+	if err != nil {
+		return err
+	}
+
+	// Keep the calibration fitted before the update.
+	updatedData.Calibration = trainingData.Calibration
+	if samples != nil {
+		updatedData.Samples = append(append([]Sample{}, trainingData.Samples...), samples...)
+	}
+	trainingData = updatedData
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package lbph
+
+import (
+	"image"
+	"testing"
+
+	"github.com/kelvins/lbph/histogram"
+	"github.com/kelvins/lbph/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqualParams(t *testing.T) {
+	params := Params{Radius: 1, Neighbors: 8, GridX: 8, GridY: 8}
+	assert.True(t, equalParams(params, params))
+	assert.False(t, equalParams(params, Params{Radius: 1, Neighbors: 8, GridX: 4, GridY: 8}))
+
+	regions := []histogram.Region{{X: 0, Y: 0, Width: 1, Height: 1}}
+	assert.False(t, equalParams(params, Params{Radius: 1, Neighbors: 8, GridX: 8, GridY: 8, Regions: regions}))
+	assert.True(t, equalParams(Params{Regions: regions}, Params{Regions: []histogram.Region{{X: 0, Y: 0, Width: 1, Height: 1}}}))
+	assert.False(t, equalParams(Params{Regions: regions}, Params{Regions: []histogram.Region{{X: 0, Y: 0, Width: 0.5, Height: 1}}}))
+}
+
+func TestUpdate(t *testing.T) {
+	Init(Params{})
+
+	var images []image.Image
+	for _, path := range []string{"./dataset/train/1.png", "./dataset/train/2.png", "./dataset/train/3.png"} {
+		img, err := LoadImage(path)
+		assert.Nil(t, err)
+		images = append(images, img)
+	}
+	labels := []string{"rocks", "grass", "wood"}
+
+	// Update an untrained algorithm should train it
+	err := Update(images[:2], labels[:2])
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(GetTrainingData().Histograms))
+
+	err = Update(images[2:], labels[2:])
+	assert.Nil(t, err)
+
+	trainData := GetTrainingData()
+	assert.Equal(t, labels, trainData.Labels)
+	assert.Nil(t, trainData.Images)
+
+	// The result should be the same as training all images
+	updatedHistograms := trainData.Histograms
+	err = Train(images, labels)
+	assert.Nil(t, err)
+	assert.Equal(t, GetTrainingData().Histograms, updatedHistograms)
+
+	test, err := LoadImage("./dataset/test/1.png")
+	assert.Nil(t, err)
+	label, _, err := Predict(test)
+	assert.Nil(t, err)
+	assert.Equal(t, "wood", label)
+
+	// Invalid parameters
+	err = Update(images, labels[:1])
+	assert.NotNil(t, err)
+
+	err = Update(nil, nil)
+	assert.NotNil(t, err)
+
+	err = Update([]image.Image{image.NewGray(image.Rect(0, 0, 20, 20))}, []string{"small"})
+	assert.NotNil(t, err)
+
+	// The LBPH parameters have changed
+	lbphParams.GridX = 4
+	err = Update(images[:1], labels[:1])
+	assert.NotNil(t, err)
+	lbphParams.GridX = 8
+
+	// The mask has changed
+	Mask = image.NewGray(images[0].Bounds())
+	err = Update(images[:1], labels[:1])
+	assert.NotNil(t, err)
+	Mask = nil
+
+	// The training data should not be changed by the errors
+	assert.Equal(t, 3, len(GetTrainingData().Histograms))
+
+	// The metric used to train the algorithm is fitted again (not the current Metric)
+	defer func() { Metric = metric.EuclideanDistance }()
+	Metric = metric.MahalanobisDistance
+	err = Train(images[:2], labels[:2])
+	assert.Nil(t, err)
+	Metric = metric.CosineDistance
+	err = Update(images[2:], labels[2:])
+	assert.Nil(t, err)
+
+	trainData = GetTrainingData()
+	assert.Equal(t, metric.MahalanobisDistance, trainData.SelectedMetric)
+	assert.NotNil(t, trainData.Metric)
+	assert.Equal(t, string(metric.MahalanobisDistance), trainData.Metric.Name())
+}